@@ -0,0 +1,348 @@
+package shoal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// bundleLockFilename is the name of the lock in the bundle.
+const bundleLockFilename = "shoal.lock.json"
+
+// BundleLock records the food versions resolved into a bundle.
+type BundleLock struct {
+	Foods []LockedFood `json:"foods"`
+}
+
+// LockedFood is a food version in a bundle.
+type LockedFood struct {
+	Rig          string `json:"rig"`
+	Food         string `json:"food"`
+	Constraint   string `json:"constraint,omitempty"`
+	Version      string `json:"version"`
+	FoodCommitID string `json:"foodCommitID"`
+	OS           string `json:"os"`
+	Arch         string `json:"arch"`
+	URL          string `json:"url"`
+	SHA256       string `json:"sha256"`
+	// Definition is the path to the Lua food definition in the bundle.
+	Definition string `json:"definition"`
+	// Archive is the path to the package archive in the bundle.
+	Archive string `json:"archive"`
+
+	// Vars are the vars the food definition was evaluated with.
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// CreateBundle resolves the dependencies declared in the config, downloads their packages for the target platform,
+// and writes a gzipped tarball containing the food definitions, the package archives, and a lock of what was resolved.
+// The bundle can be installed with InstallBundle without any access to git repositories or the Internet.
+func (a *App) CreateBundle(config Config, w io.Writer) error {
+	a.setEnv()
+
+	var (
+		lock BundleLock
+		// scripts maps the paths in the bundle to the food definitions
+		scripts = map[string]string{}
+		// archives maps the paths in the bundle to the cached package archives
+		archives = map[string]string{}
+	)
+
+	goos, goarch := a.platform()
+
+	for _, d := range config.dependencies() {
+		d, ok := d.forPlatform(goos, goarch)
+		if !ok {
+			a.skipDependency(d, goos, goarch)
+			continue
+		}
+
+		version, err := a.resolve(d.Rig, d.Food, d.Version, d.Vars)
+		if err != nil {
+			return ErrDependency{Rig: d.Rig, Food: d.Food, Constraint: d.Version, Err: err}
+		}
+
+		f := version.food
+
+		pkg, err := a.getPackage(&f)
+		if err != nil {
+			return ErrDependency{Rig: d.Rig, Food: d.Food, Constraint: d.Version, Err: err}
+		}
+
+		archive, err := a.fetchPackage(&f, pkg)
+		if err != nil {
+			return ErrDependency{Rig: d.Rig, Food: d.Food, Constraint: d.Version, Err: ErrInstall{Food: f.Name, Version: f.Version, Err: err}}
+		}
+
+		locked := LockedFood{
+			Rig:          d.Rig,
+			Food:         d.Food,
+			Constraint:   d.Version,
+			Vars:         d.Vars,
+			Version:      f.Version,
+			FoodCommitID: version.foodCommitID,
+			OS:           pkg.OS,
+			Arch:         pkg.Arch,
+			URL:          pkg.URL,
+			SHA256:       pkg.SHA256,
+			// Namespaced by the rig, as rigs can have different definitions of the same food version
+			Definition: path.Join("foods", rigKey(d.Rig), fmt.Sprintf("%s-%s.lua", f.Name, f.Version)),
+			Archive:    path.Join("archives", rigKey(d.Rig), filepath.Base(archive)),
+		}
+
+		scripts[locked.Definition] = version.script
+		archives[locked.Archive] = archive
+
+		lock.Foods = append(lock.Foods, locked)
+
+		a.logger.Printf("bundled %s %s for %s/%s", f.Name, f.Version, pkg.OS, pkg.Arch)
+	}
+
+	if config.Helm.Plugins.Diff != "" {
+		a.skipHelmDiff(config.Helm.Plugins.Diff, "helm plugins are not bundled")
+	}
+
+	lockJSON, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := writeTarFile(tw, bundleLockFilename, int64(len(lockJSON)), bytes.NewReader(lockJSON)); err != nil {
+		return err
+	}
+
+	for _, l := range lock.Foods {
+		script := scripts[l.Definition]
+
+		if err := writeTarFile(tw, l.Definition, int64(len(script)), strings.NewReader(script)); err != nil {
+			return err
+		}
+
+		if err := writeTarArchive(tw, l.Archive, archives[l.Archive]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("writing %s to bundle: %w", name, err)
+	}
+
+	if _, err := io.Copy(tw, r); err != nil {
+		return fmt.Errorf("writing %s to bundle: %w", name, err)
+	}
+
+	return nil
+}
+
+func writeTarArchive(tw *tar.Writer, name, archive string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return writeTarFile(tw, name, info.Size(), f)
+}
+
+// InstallBundle installs all the foods in the bundle created by CreateBundle.
+// The food definitions and the package archives are read from the bundle, and the sha256 of each archive is verified
+// against its food definition. No git repository or URL is accessed.
+func (a *App) InstallBundle(r io.Reader) error {
+	a.setEnv()
+
+	dir, err := ioutil.TempDir("", "shoal-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := extractBundle(r, dir); err != nil {
+		return err
+	}
+
+	bs, err := ioutil.ReadFile(filepath.Join(dir, bundleLockFilename))
+	if err != nil {
+		return fmt.Errorf("reading bundle lock: %w", err)
+	}
+
+	var lock BundleLock
+
+	if err := json.Unmarshal(bs, &lock); err != nil {
+		return fmt.Errorf("parsing bundle lock: %w", err)
+	}
+
+	return a.withGeneration(func(gen *generation) error {
+		for _, l := range lock.Foods {
+			if err := a.installLockedFood(gen, dir, l); err != nil {
+				a.emit(Event{
+					Type:       EventFailed,
+					Rig:        l.Rig,
+					Food:       l.Food,
+					Constraint: l.Constraint,
+					Version:    l.Version,
+					Err:        err,
+				})
+
+				return ErrDependency{Rig: l.Rig, Food: l.Food, Constraint: l.Constraint, Err: err}
+			}
+		}
+
+		return nil
+	})
+}
+
+func (a *App) installLockedFood(gen *generation, dir string, l LockedFood) error {
+	script, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(l.Definition)))
+	if err != nil {
+		return fmt.Errorf("reading food definition: %w", err)
+	}
+
+	food, err := a.evalFood(string(script), l.OS, l.Arch, l.Vars)
+	if err != nil {
+		return fmt.Errorf("evaluating food definition %s: %w", l.Definition, err)
+	}
+
+	pkg := food.GetPackage(l.OS, l.Arch)
+	if pkg == nil {
+		return ErrInstall{Food: food.Name, Version: food.Version, Err: fmt.Errorf("food does not support the platform (%s/%s)", l.OS, l.Arch)}
+	}
+
+	archive := filepath.Join(dir, filepath.FromSlash(l.Archive))
+
+	// The lock is only informational. The food definition decides the sha256 to trust, as it does for downloads
+	if err := checksumVerifyPath(archive, pkg.SHA256); err != nil {
+		return ErrInstall{Food: food.Name, Version: food.Version, Err: fmt.Errorf("shasum verify check failed: %v", err)}
+	}
+
+	cachedFilePath, err := cachedPackagePath(&food, pkg)
+	if err != nil {
+		return err
+	}
+
+	// Place the archive in the download cache, so that installing it doesn't access the URL
+	if err := copyFile(archive, cachedFilePath); err != nil {
+		return err
+	}
+
+	version := &versionedFood{
+		foodCommitID: l.FoodCommitID,
+		food:         food,
+		script:       string(script),
+	}
+
+	return a.installVersion(gen, l.Rig, l.Food, l.Constraint, version, pkg)
+}
+
+// extractBundle extracts the regular files in the gzipped tarball into dir.
+func extractBundle(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("reading bundle: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("reading bundle: %w", err)
+		}
+
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(h.Name)
+
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("reading bundle: invalid file path %q", h.Name)
+		}
+
+		p := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+
+		f, err := os.Create(p)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return fmt.Errorf("reading bundle: %w", err)
+		}
+
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+}
+
+// copyFile copies src to dst, replacing dst atomically.
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".download"
+
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, dst)
+}
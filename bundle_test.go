@@ -0,0 +1,165 @@
+package shoal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestBundle(t *testing.T) {
+	host := runtime.GOOS + "/" + runtime.GOARCH
+
+	packages := newTestPackages(t)
+
+	// Two rigs with their own definitions of the same food version
+	food := packages.food(t, "foo", "1.0.0", host)
+
+	rig1 := testRig(t, "-- rig 1\n"+food)
+	defer os.RemoveAll(rig1)
+
+	rig2 := testRig(t, "-- rig 2\n"+food)
+	defer os.RemoveAll(rig2)
+
+	config := Config{Dependencies: []Dependency{
+		{Rig: rig1, Food: "foo", Version: "1.0.0"},
+		{Rig: rig2, Food: "foo", Version: "1.0.0"},
+	}}
+
+	var bundle bytes.Buffer
+
+	if err := testApp(t, testRoot(t), config).CreateBundle(config, &bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bundle is installed without accessing the package server
+	packages.Close()
+
+	dir := testBundleDir(t, bundle.Bytes())
+
+	var lock BundleLock
+
+	bs, err := ioutil.ReadFile(filepath.Join(dir, bundleLockFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(bs, &lock); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lock.Foods) != 2 || lock.Foods[0].Definition == lock.Foods[1].Definition {
+		t.Fatalf("want a definition per rig, got %+v", lock.Foods)
+	}
+
+	for i, rig := range []string{rig1, rig2} {
+		script, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(lock.Foods[i].Definition)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := testRigFile(t, rig, "Food/foo.lua"); string(script) != want {
+			t.Errorf("want the definition in %s, got %q", rig, script)
+		}
+	}
+
+	root := testRoot(t)
+
+	if err := testApp(t, root, Config{}).InstallBundle(bytes.NewReader(bundle.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := readLink(t, filepath.Join(root, "bin"), "foo"), packageContent("foo", "1.0.0", host); got != want {
+		t.Errorf("want foo 1.0.0 to be installed, got %q", got)
+	}
+
+	// A tampered archive is rejected as its sha256 doesn't match the food definition
+	if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(lock.Foods[0].Archive)), tarGz(t, "foo", "evil"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root = testRoot(t)
+
+	err = testApp(t, root, Config{}).InstallBundle(bytes.NewReader(testBundle(t, dir)))
+
+	var install ErrInstall
+
+	if !errors.As(err, &install) {
+		t.Fatalf("want ErrInstall, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "bin", "foo")); !os.IsNotExist(err) {
+		t.Errorf("want nothing to be installed from the tampered bundle, got %v", err)
+	}
+}
+
+// testBundleDir extracts the bundle into a temporary directory.
+func testBundleDir(t *testing.T, bundle []byte) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "shoal-bundle-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	if err := extractBundle(bytes.NewReader(bundle), dir); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+// testBundle creates a bundle from the files in the directory.
+func testBundle(t *testing.T, dir string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		return writeTarArchive(tw, filepath.ToSlash(rel), path)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func testRigFile(t *testing.T, rig, path string) string {
+	t.Helper()
+
+	bs, err := ioutil.ReadFile(filepath.Join(rig, filepath.FromSlash(path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(bs)
+}
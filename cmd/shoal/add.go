@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/mumoshu/shoal"
+)
+
+func (c *cli) add(args []string) {
+	addFlags := flag.NewFlagSet("add", flag.ExitOnError)
+
+	var (
+		rig, rootDir string
+		sync         bool
+	)
+
+	addFlags.StringVar(&rig, "rig", "", "Rig to read the food from. Defaults to the top-level rig")
+	addFlags.BoolVar(&sync, "sync", false, "Sync the config after adding the dependency")
+	addFlags.StringVar(&rootDir, "root", shoal.DefaultRootDir, "Directory to install foods into with --sync")
+
+	addFlags.Parse(args)
+
+	if addFlags.NArg() < 1 || addFlags.NArg() > 2 {
+		c.fatalf("Usage: shoal add [--rig RIG] [--sync] [--root DIR] FOOD [CONSTRAINT]")
+	}
+
+	food, constraint := addFlags.Arg(0), addFlags.Arg(1)
+
+	config := c.loadConfig()
+
+	resolveRig := rig
+	if resolveRig == "" {
+		resolveRig = config.Rig
+	}
+
+	if resolveRig == "" {
+		c.fatalf("No rig found for %s: specify --rig or set rig in %s", food, c.configFile)
+	}
+
+	app := c.newApp(config, shoal.WithRootDir(rootDir))
+
+	// Fail before touching the config when the food doesn't exist or no version matches
+	version, err := app.Resolve(resolveRig, food, constraint)
+	if err != nil {
+		c.exit(err)
+	}
+
+	file := c.openConfigFile()
+
+	if err := file.SetDependency(rig, food, constraint); err != nil {
+		c.fatalf("Error adding food %s to %s: %v", food, c.configFile, err)
+	}
+
+	c.saveConfigFile(file)
+
+	c.logger.Infof("Added food %s %s to %s", food, version.Version, c.configFile)
+
+	if sync {
+		c.syncConfig(app)
+	}
+}
+
+func (c *cli) remove(args []string) {
+	removeFlags := flag.NewFlagSet("remove", flag.ExitOnError)
+
+	var (
+		rig, rootDir string
+		sync         bool
+	)
+
+	removeFlags.StringVar(&rig, "rig", "", "Only remove the dependency on the food in the rig. Defaults to any rig")
+	removeFlags.BoolVar(&sync, "sync", false, "Sync the config after removing the dependency")
+	removeFlags.StringVar(&rootDir, "root", shoal.DefaultRootDir, "Directory to install foods into with --sync")
+
+	removeFlags.Parse(args)
+
+	if removeFlags.NArg() != 1 {
+		c.fatalf("Usage: shoal remove [--rig RIG] [--sync] [--root DIR] FOOD")
+	}
+
+	food := removeFlags.Arg(0)
+
+	file := c.openConfigFile()
+
+	removed, err := file.RemoveDependency(rig, food)
+	if err != nil {
+		c.fatalf("Error removing food %s from %s: %v", food, c.configFile, err)
+	}
+
+	if !removed {
+		c.fatalf("Food %s is not declared in %s", food, c.configFile)
+	}
+
+	c.saveConfigFile(file)
+
+	c.logger.Infof("Removed food %s from %s", food, c.configFile)
+
+	if sync {
+		c.syncConfig(c.newApp(c.loadConfig(), shoal.WithRootDir(rootDir)))
+	}
+}
+
+// openConfigFile reads the config file for editing. Unlike loadConfig, the files it includes aren't read.
+func (c *cli) openConfigFile() *shoal.ConfigFile {
+	file, err := shoal.OpenConfigFile(c.configFile)
+	if err != nil {
+		c.logger.Errorf("Error loading config file %q: %v", c.configFile, err)
+		os.Exit(exitCode(err))
+	}
+
+	return file
+}
+
+func (c *cli) saveConfigFile(file *shoal.ConfigFile) {
+	if err := file.Save(); err != nil {
+		c.fatalf("Error writing config file %q: %v", c.configFile, err)
+	}
+}
+
+// syncConfig reloads the edited config and syncs it.
+func (c *cli) syncConfig(app *shoal.App) {
+	config := c.loadConfig()
+
+	if err := app.Sync(*config); err != nil {
+		c.exit(err)
+	}
+}
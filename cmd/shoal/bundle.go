@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/mumoshu/shoal"
+)
+
+func (c *cli) bundle(args []string) {
+	var sub string
+
+	if len(args) > 0 {
+		sub, args = args[0], args[1:]
+	}
+
+	switch sub {
+	case "create":
+		c.bundleCreate(args)
+	case "install":
+		c.bundleInstall(args)
+	default:
+		c.fatalf("Usage: shoal bundle create|install")
+	}
+}
+
+func (c *cli) bundleCreate(args []string) {
+	createFlags := flag.NewFlagSet("bundle create", flag.ExitOnError)
+
+	var (
+		output, targetOS, targetArch string
+		strictFoods                  bool
+	)
+
+	createFlags.StringVar(&output, "o", "shoal-bundle.tar.gz", "Path to the bundle to create")
+	createFlags.StringVar(&targetOS, "os", runtime.GOOS, "Bundle packages for the OS instead of the host one")
+	createFlags.StringVar(&targetArch, "arch", runtime.GOARCH, "Bundle packages for the architecture instead of the host one")
+	createFlags.BoolVar(&strictFoods, "strict-foods", false, "Fail when the selected revision of a food definition or any newer one is rotten")
+
+	createFlags.Parse(args)
+
+	config := c.loadConfig()
+
+	app := c.newApp(config, shoal.Target(targetOS, targetArch), shoal.StrictFoods(strictFoods))
+
+	f, err := os.Create(output)
+	if err != nil {
+		c.fatalf("Error creating %s: %v", output, err)
+	}
+
+	if err := app.CreateBundle(*config, f); err != nil {
+		f.Close()
+		os.Remove(output)
+		c.exit(err)
+	}
+
+	if err := f.Close(); err != nil {
+		c.fatalf("Error writing %s: %v", output, err)
+	}
+
+	fmt.Fprintf(os.Stdout, "Created %s\n", output)
+}
+
+func (c *cli) bundleInstall(args []string) {
+	installFlags := flag.NewFlagSet("bundle install", flag.ExitOnError)
+
+	var rootDir string
+
+	installFlags.StringVar(&rootDir, "root", shoal.DefaultRootDir, "Directory to install foods into")
+
+	installFlags.Parse(args)
+
+	if installFlags.NArg() != 1 {
+		c.fatalf("Usage: shoal bundle install [--root DIR] BUNDLE")
+	}
+
+	bundle := installFlags.Arg(0)
+
+	// Installing a bundle needs neither the config nor git
+	app := c.newApp(nil, shoal.WithRootDir(rootDir))
+
+	if err := app.Init(); err != nil {
+		c.exit(err)
+	}
+
+	f, err := os.Open(bundle)
+	if err != nil {
+		c.fatalf("Error opening %s: %v", bundle, err)
+	}
+	defer f.Close()
+
+	if err := app.InstallBundle(f); err != nil {
+		c.exit(err)
+	}
+}
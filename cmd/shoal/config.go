@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+func (c *cli) config(args []string) {
+	if len(args) == 0 || args[0] != "view" {
+		c.fatalf("Usage: shoal config view")
+	}
+
+	config := c.loadConfig()
+
+	e := yaml.NewEncoder(os.Stdout)
+	e.SetIndent(2)
+
+	if err := e.Encode(config); err != nil {
+		c.fatalf("Error encoding config: %v", err)
+	}
+
+	if err := e.Close(); err != nil {
+		c.fatalf("Error encoding config: %v", err)
+	}
+}
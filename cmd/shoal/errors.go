@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/mumoshu/shoal"
+)
+
+// Exit codes returned by the shoal command, so that scripts can tell failures apart without parsing messages.
+const (
+	exitCodeError             = 1
+	exitCodeInvalidConfig     = 2
+	exitCodeNoMatchingVersion = 3
+	exitCodeGit               = 4
+	exitCodeInstall           = 5
+	exitCodeBrokenCache       = 6
+	exitCodeRottenFood        = 7
+)
+
+func exitCode(err error) int {
+	var (
+		noMatchingVersion shoal.ErrNoMatchingVersion
+		gitErr            shoal.ErrGit
+		installErr        shoal.ErrInstall
+		brokenCache       shoal.ErrBrokenCache
+		invalidConfig     shoal.ErrInvalidConfig
+		rottenFoods       shoal.ErrRottenFoods
+	)
+
+	switch {
+	case errors.As(err, &invalidConfig):
+		return exitCodeInvalidConfig
+	case errors.As(err, &rottenFoods):
+		return exitCodeRottenFood
+	case errors.As(err, &noMatchingVersion):
+		return exitCodeNoMatchingVersion
+	case errors.As(err, &gitErr):
+		return exitCodeGit
+	case errors.As(err, &installErr):
+		return exitCodeInstall
+	case errors.As(err, &brokenCache):
+		return exitCodeBrokenCache
+	default:
+		return exitCodeError
+	}
+}
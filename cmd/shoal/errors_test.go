@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mumoshu/shoal"
+)
+
+func TestExitCode(t *testing.T) {
+	noMatchingVersion := shoal.ErrDependency{Rig: "r", Food: "bar", Err: shoal.ErrNoMatchingVersion{Rig: "r", Food: "bar"}}
+	install := shoal.ErrDependency{Rig: "r", Food: "foo", Err: shoal.ErrInstall{Food: "foo", Version: "1.0.0", Err: errors.New("404")}}
+
+	testcases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "unknown", err: errors.New("failed"), want: exitCodeError},
+		{name: "dependency", err: install, want: exitCodeInstall},
+		{name: "wrapped", err: fmt.Errorf("syncing: %w", install), want: exitCodeInstall},
+		// The keep-going mode exits with the code of the most specific failure among the dependencies
+		{name: "keep-going", err: shoal.ErrDependencies{install, noMatchingVersion}, want: exitCodeNoMatchingVersion},
+		{name: "keep-going install", err: shoal.ErrDependencies{install}, want: exitCodeInstall},
+		{name: "rotten", err: shoal.ErrRottenFoods{{Rig: "r", Food: "foo", Err: errors.New("syntax error")}}, want: exitCodeRottenFood},
+		{name: "invalid config", err: shoal.ErrInvalidConfig{}, want: exitCodeInvalidConfig},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := exitCode(tc.err); got != tc.want {
+				t.Errorf("want exit code %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
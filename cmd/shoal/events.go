@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mumoshu/shoal"
+	"github.com/sirupsen/logrus"
+)
+
+// eventRenderer logs events emitted by shoal, with the rig, food, version and commit as fields.
+type eventRenderer struct {
+	l *logrus.Logger
+}
+
+func (r *eventRenderer) handle(e shoal.Event) {
+	fields := logrus.Fields{
+		"event": string(e.Type),
+		"food":  e.Food,
+	}
+
+	if e.Rig != "" {
+		fields["rig"] = e.Rig
+	}
+
+	if e.Constraint != "" {
+		fields["constraint"] = e.Constraint
+	}
+
+	if e.Version != "" {
+		fields["version"] = e.Version
+	}
+
+	if e.FoodCommitID != "" {
+		fields["commit"] = e.FoodCommitID
+	}
+
+	entry := r.l.WithFields(fields)
+
+	switch e.Type {
+	case shoal.EventResolveStarted:
+		entry.Infof("Resolving %s %s", e.Food, e.Constraint)
+	case shoal.EventVersionsListed:
+		entry.WithField("versions", e.Versions).Debugf("Found %d versions of %s", len(e.Versions), e.Food)
+	case shoal.EventVersionSelected:
+		entry.Infof("Selected %s %s from commit %s", e.Food, e.Version, shortCommitID(e.FoodCommitID))
+	case shoal.EventDownloadProgress:
+		entry = entry.WithFields(logrus.Fields{
+			"url":             e.URL,
+			"bytesDownloaded": e.BytesDownloaded,
+			"bytesTotal":      e.BytesTotal,
+		})
+
+		if e.BytesTotal > 0 {
+			entry.Infof("Downloading %s %s: %s / %s", e.Food, e.Version, humanBytes(e.BytesDownloaded), humanBytes(e.BytesTotal))
+		} else {
+			entry.Infof("Downloading %s %s: %s", e.Food, e.Version, humanBytes(e.BytesDownloaded))
+		}
+	case shoal.EventInstalled:
+		entry.Infof("Installed %s %s", e.Food, e.Version)
+
+		if e.Caveats != "" {
+			entry.Infof("%s %s caveats:\n%s", e.Food, e.Version, e.Caveats)
+		}
+	case shoal.EventSkipped:
+		entry.WithField("reason", e.Reason).Infof("Skipped %s: %s", strings.TrimSpace(e.Food+" "+e.Version), e.Reason)
+	case shoal.EventFailed:
+		entry.WithError(e.Err).Errorf("Failed %s %s", e.Food, e.Constraint)
+	case shoal.EventRottenFood:
+		entry.WithError(e.Err).Warnf("Skipped rotten %s from commit %s", e.Food, shortCommitID(e.FoodCommitID))
+	case shoal.EventRetrying:
+		if e.URL != "" {
+			entry = entry.WithField("url", e.URL)
+		}
+
+		entry.WithError(e.Err).WithFields(logrus.Fields{
+			"attempt": e.Attempt,
+			"delay":   e.Delay.String(),
+		}).Warnf("Retrying %s in %s after attempt %d failed", e.Reason, e.Delay, e.Attempt)
+	case shoal.EventDirectoryCreated:
+		r.l.WithField("event", string(e.Type)).WithField("dir", e.Dir).Infof("Created %s", e.Dir)
+	}
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
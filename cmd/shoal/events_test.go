@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mumoshu/shoal"
+	"github.com/sirupsen/logrus"
+)
+
+func TestEventRenderer(t *testing.T) {
+	testcases := []struct {
+		name      string
+		event     shoal.Event
+		wantLevel string
+		wantMsg   string
+		want      map[string]interface{}
+	}{
+		{
+			name:      "resolve started",
+			event:     shoal.Event{Type: shoal.EventResolveStarted, Rig: "r", Food: "helm", Constraint: ">= 3.3.0"},
+			wantLevel: "info",
+			wantMsg:   "Resolving helm >= 3.3.0",
+			want:      map[string]interface{}{"event": "ResolveStarted", "rig": "r", "food": "helm", "constraint": ">= 3.3.0"},
+		},
+		{
+			name:      "version selected",
+			event:     shoal.Event{Type: shoal.EventVersionSelected, Rig: "r", Food: "helm", Version: "3.3.4", FoodCommitID: "0123456789abcdef"},
+			wantLevel: "info",
+			wantMsg:   "Selected helm 3.3.4 from commit 01234567",
+			want:      map[string]interface{}{"event": "VersionSelected", "version": "3.3.4", "commit": "0123456789abcdef"},
+		},
+		{
+			name:      "download progress",
+			event:     shoal.Event{Type: shoal.EventDownloadProgress, Food: "helm", Version: "3.3.4", URL: "https://example.com/helm.tar.gz", BytesDownloaded: 1536, BytesTotal: 3 * 1024 * 1024},
+			wantLevel: "info",
+			wantMsg:   "Downloading helm 3.3.4: 1.5 KiB / 3.0 MiB",
+			want:      map[string]interface{}{"url": "https://example.com/helm.tar.gz", "bytesDownloaded": 1536.0, "bytesTotal": 3145728.0},
+		},
+		{
+			name:      "download progress without size",
+			event:     shoal.Event{Type: shoal.EventDownloadProgress, Food: "helm", Version: "3.3.4", BytesDownloaded: 100, BytesTotal: -1},
+			wantLevel: "info",
+			wantMsg:   "Downloading helm 3.3.4: 100 B",
+		},
+		{
+			name:      "installed",
+			event:     shoal.Event{Type: shoal.EventInstalled, Food: "helm", Version: "3.3.4"},
+			wantLevel: "info",
+			wantMsg:   "Installed helm 3.3.4",
+			want:      map[string]interface{}{"event": "Installed"},
+		},
+		{
+			name:      "skipped",
+			event:     shoal.Event{Type: shoal.EventSkipped, Food: "helm", Constraint: "3.3.4", Reason: "not for darwin/arm64"},
+			wantLevel: "info",
+			wantMsg:   "Skipped helm: not for darwin/arm64",
+			want:      map[string]interface{}{"reason": "not for darwin/arm64"},
+		},
+		{
+			name:      "failed",
+			event:     shoal.Event{Type: shoal.EventFailed, Food: "helm", Constraint: ">= 4.0.0", Err: errors.New("no matching version")},
+			wantLevel: "error",
+			wantMsg:   "Failed helm >= 4.0.0",
+			want:      map[string]interface{}{"error": "no matching version"},
+		},
+		{
+			name:      "rotten food",
+			event:     shoal.Event{Type: shoal.EventRottenFood, Food: "helm", FoodCommitID: "fedcba9876543210", Err: errors.New("syntax error")},
+			wantLevel: "warning",
+			wantMsg:   "Skipped rotten helm from commit fedcba98",
+			want:      map[string]interface{}{"commit": "fedcba9876543210", "error": "syntax error"},
+		},
+		{
+			name:      "retrying",
+			event:     shoal.Event{Type: shoal.EventRetrying, Reason: "git clone r", Attempt: 1, Delay: time.Second, Err: errors.New("timeout")},
+			wantLevel: "warning",
+			wantMsg:   "Retrying git clone r in 1s after attempt 1 failed",
+			want:      map[string]interface{}{"attempt": 1.0, "delay": "1s", "error": "timeout"},
+		},
+		{
+			name:      "directory created",
+			event:     shoal.Event{Type: shoal.EventDirectoryCreated, Dir: "/tmp/shoal/rigs"},
+			wantLevel: "info",
+			wantMsg:   "Created /tmp/shoal/rigs",
+			want:      map[string]interface{}{"event": "DirectoryCreated", "dir": "/tmp/shoal/rigs"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries := renderEvents(t, logrus.InfoLevel, tc.event)
+
+			if len(entries) != 1 {
+				t.Fatalf("want 1 log entry, got %v", entries)
+			}
+
+			got := entries[0]
+
+			if got["level"] != tc.wantLevel || got["msg"] != tc.wantMsg {
+				t.Errorf("want %s %q, got %s %q", tc.wantLevel, tc.wantMsg, got["level"], got["msg"])
+			}
+
+			for k, v := range tc.want {
+				if got[k] != v {
+					t.Errorf("want %s=%v, got %v", k, v, got[k])
+				}
+			}
+		})
+	}
+}
+
+func TestEventRendererLevels(t *testing.T) {
+	listed := shoal.Event{Type: shoal.EventVersionsListed, Food: "helm", Versions: []string{"3.3.4", "3.3.3"}}
+
+	if entries := renderEvents(t, logrus.InfoLevel, listed); len(entries) != 0 {
+		t.Errorf("want VersionsListed to be hidden at the info level, got %v", entries)
+	}
+
+	if entries := renderEvents(t, logrus.DebugLevel, listed); len(entries) != 1 || entries[0]["msg"] != "Found 2 versions of helm" {
+		t.Errorf("want VersionsListed at the debug level, got %v", entries)
+	}
+
+	// The caveats are shown at the info level, unlike the rest of what shoal logs
+	installed := shoal.Event{Type: shoal.EventInstalled, Food: "helm", Version: "3.3.4", Caveats: "run helm init"}
+
+	entries := renderEvents(t, logrus.InfoLevel, installed)
+
+	if len(entries) != 2 || entries[1]["msg"] != "helm 3.3.4 caveats:\nrun helm init" {
+		t.Errorf("want the caveats to be logged, got %v", entries)
+	}
+}
+
+// renderEvents renders the events with a JSON logger at the level, and returns the decoded log entries.
+func renderEvents(t *testing.T, level logrus.Level, events ...shoal.Event) []map[string]interface{} {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	l := logrus.New()
+	l.SetOutput(&buf)
+	l.SetFormatter(&logrus.JSONFormatter{})
+	l.SetLevel(level)
+
+	r := &eventRenderer{l: l}
+
+	for _, e := range events {
+		r.handle(e)
+	}
+
+	var entries []map[string]interface{}
+
+	d := json.NewDecoder(&buf)
+
+	for d.More() {
+		var entry map[string]interface{}
+
+		if err := d.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
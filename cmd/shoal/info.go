@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"text/tabwriter"
+
+	"github.com/mumoshu/shoal"
+)
+
+func (c *cli) info(args []string) {
+	infoFlags := flag.NewFlagSet("info", flag.ExitOnError)
+
+	var (
+		format               string
+		targetOS, targetArch string
+		rootDir              string
+	)
+
+	infoFlags.StringVar(&format, "format", "text", "Output format. Either text or json")
+	infoFlags.StringVar(&targetOS, "os", runtime.GOOS, "Show the package for the OS instead of the host one")
+	infoFlags.StringVar(&targetArch, "arch", runtime.GOARCH, "Show the package for the architecture instead of the host one")
+	infoFlags.StringVar(&rootDir, "root", shoal.DefaultRootDir, "Directory foods are installed into")
+
+	infoFlags.Parse(args)
+
+	if infoFlags.NArg() != 1 {
+		c.fatalf("Usage: shoal info [--format text|json] [--os OS] [--arch ARCH] [--root DIR] FOOD")
+	}
+
+	if format != "text" && format != "json" {
+		c.fatalf("Unknown format %q: must be either text or json", format)
+	}
+
+	config := c.loadConfig()
+
+	app := c.newReadOnlyApp(config, shoal.Target(targetOS, targetArch), shoal.WithRootDir(rootDir))
+
+	info, err := app.Info(*config, infoFlags.Arg(0))
+	if err != nil {
+		c.exit(err)
+	}
+
+	if format == "json" {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+
+		if err := e.Encode(info); err != nil {
+			c.fatalf("Error encoding food info: %v", err)
+		}
+
+		return
+	}
+
+	printInfo(info)
+}
+
+func printInfo(info *shoal.FoodInfo) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", info.Food)
+	fmt.Fprintf(w, "Version:\t%s\n", info.Version)
+	fmt.Fprintf(w, "Constraint:\t%s\n", dash(info.Constraint))
+	fmt.Fprintf(w, "Installed:\t%s\n", dash(info.InstalledVersion))
+	fmt.Fprintf(w, "Rig:\t%s\n", info.Rig)
+	fmt.Fprintf(w, "Commit:\t%s\n", info.FoodCommitID)
+	fmt.Fprintf(w, "Description:\t%s\n", dash(info.Description))
+	fmt.Fprintf(w, "Homepage:\t%s\n", dash(info.Homepage))
+	fmt.Fprintf(w, "License:\t%s\n", dash(info.License))
+
+	w.Flush()
+
+	fmt.Fprintln(os.Stdout)
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(w, "OS\tARCH\tTARGET\tURL\tSHA256")
+
+	var target *shoal.PackageInfo
+
+	for i, p := range info.Packages {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.OS, p.Arch, yesNo(p.Target), p.URL, p.SHA256)
+
+		if p.Target {
+			target = &info.Packages[i]
+		}
+	}
+
+	w.Flush()
+
+	if target == nil {
+		return
+	}
+
+	fmt.Fprintln(os.Stdout)
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(w, "PATH\tINSTALL PATH\tUNPACKED\tLINKED\tLINK")
+
+	for _, r := range target.Resources {
+		link := r.LinkPath
+
+		if r.LinkTarget != "" && !r.LinkExists {
+			// Likely a link to another version
+			link += " -> " + r.LinkTarget
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Path, r.InstallPath, yesNo(r.BarrelExists), yesNo(r.LinkExists), link)
+	}
+
+	w.Flush()
+
+	if info.Caveats != "" {
+		fmt.Fprintf(os.Stdout, "\nCaveats:\n%s\n", info.Caveats)
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newLogger(format, level string) (*logrus.Logger, error) {
+	l := logrus.New()
+
+	switch format {
+	case "json":
+		l.SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		l.SetFormatter(&logrus.TextFormatter{})
+	default:
+		return nil, fmt.Errorf("invalid log format %q: must be either json or text", format)
+	}
+
+	lv, err := logrus.ParseLevel(level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level: %w", err)
+	}
+
+	l.SetLevel(lv)
+
+	return l, nil
+}
+
+// logWriter writes the lines logged by shoal's log.Logger to the structured logger at the debug level.
+// What the user needs to see, like the caveats of the installed foods, is logged by eventRenderer at the info level.
+// The `file.go:123: ` prefix added by log.Lshortfile is moved to the `caller` field.
+type logWriter struct {
+	l *logrus.Logger
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+
+	entry := logrus.NewEntry(w.l)
+
+	if items := strings.SplitN(msg, ": ", 2); len(items) == 2 && strings.Contains(items[0], ".go:") && !strings.Contains(items[0], " ") {
+		entry = entry.WithField("caller", items[0])
+		msg = items[1]
+	}
+
+	entry.Debug(msg)
+
+	return len(p), nil
+}
@@ -3,51 +3,225 @@ package main
 import (
 	"flag"
 	"fmt"
-	"github.com/mumoshu/shoal"
-	"gopkg.in/yaml.v2"
 	"os"
+	"runtime"
+	"text/tabwriter"
+
+	"github.com/mumoshu/shoal"
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
-	var configFile string
+	var configFile, logFormat, logLevel string
 
 	flag.StringVar(&configFile, "f", "shoal.yaml", "Path to the config file")
+	flag.StringVar(&logFormat, "log-format", "text", "Log format. Either text or json")
+	flag.StringVar(&logLevel, "log-level", "info", "Log level. One of debug, info, warn and error")
 
 	flag.Parse()
 
-	if flag.Arg(0) == "version" {
+	var args []string
+
+	if flag.NArg() > 1 {
+		args = flag.Args()[1:]
+	}
+
+	switch flag.Arg(0) {
+	case "version":
 		fmt.Fprintf(os.Stdout, "%s\n", shoal.Version)
 		os.Exit(0)
+	case "schema":
+		schema, err := shoal.JSONSchema()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeError)
+		}
+
+		os.Stdout.Write(schema)
+		os.Exit(0)
 	}
 
-	f, err := os.Open(configFile)
+	logger, err := newLogger(logFormat, logLevel)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", configFile, err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeError)
 	}
 
-	var config shoal.Config
-
-	d := yaml.NewDecoder(f)
+	c := &cli{
+		configFile: configFile,
+		logger:     logger,
+	}
 
-	if err := d.Decode(&config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error decoding yaml file %q: %v\n", configFile, err)
-		os.Exit(1)
+	switch cmd := flag.Arg(0); cmd {
+	case "rollback":
+		c.rollback()
+	case "validate":
+		c.validate(args)
+	case "config":
+		c.config(args)
+	case "bundle":
+		c.bundle(args)
+	case "versions":
+		c.versions(args)
+	case "search":
+		c.search(args)
+	case "info":
+		c.info(args)
+	case "add":
+		c.add(args)
+	case "remove":
+		c.remove(args)
+	case "upgrade":
+		c.upgrade(args)
+	case "", "sync":
+		c.sync(args)
+	default:
+		logger.Errorf("Unknown command %q", cmd)
+		os.Exit(exitCodeError)
 	}
+}
+
+type cli struct {
+	configFile string
+	logger     *logrus.Logger
+}
+
+func (c *cli) fatalf(format string, args ...interface{}) {
+	c.logger.Errorf(format, args...)
+	os.Exit(exitCodeError)
+}
 
-	app, err := shoal.New()
+func (c *cli) exit(err error) {
+	c.logger.Error(err)
+	os.Exit(exitCode(err))
+}
+
+// loadConfig loads the config file along with the files it includes.
+func (c *cli) loadConfig() *shoal.Config {
+	config, err := shoal.LoadConfig(c.configFile)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error %v\n", err)
-		os.Exit(1)
+		c.logger.Errorf("Error loading config file %q: %v", c.configFile, err)
+		os.Exit(exitCode(err))
+	}
+
+	return config
+}
+
+// newApp creates and initializes the app for the config.
+func (c *cli) newApp(config *shoal.Config, opts ...shoal.Option) *shoal.App {
+	app := c.newReadOnlyApp(config, opts...)
+
+	if config == nil {
+		return app
 	}
 
 	if err := app.Init(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		c.exit(err)
+	}
+
+	return app
+}
+
+// newReadOnlyApp creates the app for the config without initializing the root dir,
+// for the commands that only read what is installed, like `sync --dry-run`.
+func (c *cli) newReadOnlyApp(config *shoal.Config, opts ...shoal.Option) *shoal.App {
+	renderer := &eventRenderer{l: c.logger}
+
+	opts = append([]shoal.Option{shoal.LogOutput(&logWriter{l: c.logger}), shoal.WithEventHandler(renderer.handle)}, opts...)
+
+	app, err := shoal.New(opts...)
+	if err != nil {
+		c.fatalf("Error %v", err)
+	}
+
+	if config == nil {
+		return app
 	}
 
-	if err := app.Sync(config); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+	if err := app.InitGitProvider(*config); err != nil {
+		c.logger.Errorf("Error: %v", err)
+		os.Exit(exitCodeInvalidConfig)
+	}
+
+	return app
+}
+
+func (c *cli) rollback() {
+	app := c.newApp(nil)
+
+	id, err := app.Rollback()
+	if err != nil {
+		c.exit(err)
+	}
+
+	fmt.Fprintf(os.Stdout, "Rolled back to generation %d\n", id)
+}
+
+func (c *cli) sync(args []string) {
+	syncFlags := flag.NewFlagSet("sync", flag.ExitOnError)
+
+	var (
+		dryRun, force, keepGoing, strictFoods bool
+		targetOS, targetArch                  string
+		rootDir                               string
+	)
+
+	syncFlags.BoolVar(&dryRun, "dry-run", false, "Print what would be installed without installing anything")
+	syncFlags.BoolVar(&force, "force", false, "Reinstall foods even when the selected versions are already installed")
+	syncFlags.BoolVar(&keepGoing, "keep-going", false, "Attempt every dependency and report all the failures, instead of stopping at the first one")
+	syncFlags.BoolVar(&strictFoods, "strict-foods", false, "Fail when the selected revision of a food definition or any newer one is rotten, instead of skipping rotten revisions")
+
+	syncFlags.StringVar(&targetOS, "os", runtime.GOOS, "Install packages for the OS instead of the host one")
+	syncFlags.StringVar(&targetArch, "arch", runtime.GOARCH, "Install packages for the architecture instead of the host one")
+	syncFlags.StringVar(&rootDir, "root", shoal.DefaultRootDir, "Directory to install foods into")
+
+	syncFlags.Parse(args)
+
+	config := c.loadConfig()
+
+	opts := []shoal.Option{shoal.Force(force), shoal.KeepGoing(keepGoing), shoal.StrictFoods(strictFoods), shoal.Target(targetOS, targetArch), shoal.WithRootDir(rootDir)}
+
+	if dryRun {
+		plan, err := c.newReadOnlyApp(config, opts...).Plan(*config)
+		if plan != nil {
+			printPlan(plan)
+		}
+
+		if err != nil {
+			c.exit(err)
+		}
+
+		return
+	}
+
+	if err := c.newApp(config, opts...).Sync(*config); err != nil {
+		c.exit(err)
+	}
+}
+
+func printPlan(plan *shoal.Plan) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(w, "FOOD\tACTION\tINSTALLED\tVERSION\tCOMMIT\tURL\tSHA256")
+
+	for _, d := range plan.Dependencies {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.Food, d.Action, dash(d.InstalledVersion), dash(d.Version), dash(shortCommitID(d.FoodCommitID)), dash(d.URL), dash(d.SHA256))
+	}
+
+	w.Flush()
+}
+
+// dash returns "-" for an empty column, like the version of a skipped dependency.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func shortCommitID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
 	}
+	return id
 }
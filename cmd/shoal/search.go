@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mumoshu/shoal"
+)
+
+// searchResult is a search result with the newest version found in the history of the rig.
+type searchResult struct {
+	shoal.SearchResult
+
+	Latest string `json:"latest,omitempty"`
+}
+
+func (c *cli) search(args []string) {
+	searchFlags := flag.NewFlagSet("search", flag.ExitOnError)
+
+	var (
+		latest bool
+		format string
+	)
+
+	searchFlags.BoolVar(&latest, "latest", false, "Also show the newest version of each food found in the history of its rig, which takes longer")
+	searchFlags.StringVar(&format, "format", "text", "Output format. Either text or json")
+
+	searchFlags.Parse(args)
+
+	if searchFlags.NArg() > 1 {
+		c.fatalf("Usage: shoal search [--latest] [--format text|json] [TERM]")
+	}
+
+	if format != "text" && format != "json" {
+		c.fatalf("Unknown format %q: must be either text or json", format)
+	}
+
+	config := c.loadConfig()
+
+	app := c.newReadOnlyApp(config)
+
+	found, err := app.Search(*config, searchFlags.Arg(0))
+	if err != nil {
+		c.exit(err)
+	}
+
+	results := make([]searchResult, len(found))
+
+	for i, r := range found {
+		results[i].SearchResult = r
+
+		if !latest {
+			continue
+		}
+
+		versions, err := app.ListVersions(r.Rig, r.Name)
+		if err != nil {
+			c.exit(err)
+		}
+
+		if v, ok := shoal.NewestVersion(versions); ok {
+			results[i].Latest = v.Version
+		}
+	}
+
+	if format == "json" {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+
+		if err := e.Encode(results); err != nil {
+			c.fatalf("Error encoding search results: %v", err)
+		}
+
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+	if latest {
+		fmt.Fprintln(w, "NAME\tLATEST\tRIG\tDESCRIPTION")
+	} else {
+		fmt.Fprintln(w, "NAME\tRIG\tDESCRIPTION")
+	}
+
+	for _, r := range results {
+		if latest {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Name, dash(r.Latest), r.Rig, dash(r.Description))
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, r.Rig, dash(r.Description))
+		}
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mumoshu/shoal"
+)
+
+func (c *cli) upgrade(args []string) {
+	upgradeFlags := flag.NewFlagSet("upgrade", flag.ExitOnError)
+
+	var major, minor, patch, dryRun bool
+
+	upgradeFlags.BoolVar(&major, "major", false, "Upgrade to any newer version. This is the default")
+	upgradeFlags.BoolVar(&minor, "minor", false, "Only upgrade to newer minor and patch versions of the same major version")
+	upgradeFlags.BoolVar(&patch, "patch", false, "Only upgrade to newer patch versions of the same minor version")
+	upgradeFlags.BoolVar(&dryRun, "dry-run", false, "Print the changes to the config without writing them")
+
+	upgradeFlags.Parse(args)
+
+	var limit shoal.UpgradeLimit
+
+	switch {
+	case countTrue(major, minor, patch) > 1:
+		c.fatalf("Only one of --major, --minor and --patch can be given")
+	case minor:
+		limit = shoal.UpgradeMinor
+	case patch:
+		limit = shoal.UpgradePatch
+	}
+
+	config := c.loadConfig()
+
+	file := c.openConfigFile()
+
+	before := file.Bytes()
+
+	app := c.newReadOnlyApp(config)
+
+	upgrades, err := app.Upgrade(file, limit, upgradeFlags.Args()...)
+	if err != nil {
+		c.exit(err)
+	}
+
+	var upgraded int
+
+	for _, u := range upgrades {
+		switch {
+		case u.Skipped != "":
+			c.logger.Warnf("Skipped upgrading %s at line %d: %s", u.Food, u.Line, u.Skipped)
+		case u.Upgraded():
+			upgraded++
+		}
+	}
+
+	if upgraded == 0 {
+		c.logger.Infof("All dependencies are up to date")
+		return
+	}
+
+	printDiff(os.Stdout, c.configFile, before, file.Bytes())
+
+	if dryRun {
+		return
+	}
+
+	c.saveConfigFile(file)
+
+	c.logger.Infof("Upgraded %d dependencies in %s", upgraded, c.configFile)
+}
+
+func countTrue(bs ...bool) int {
+	var n int
+
+	for _, b := range bs {
+		if b {
+			n++
+		}
+	}
+
+	return n
+}
+
+// diffContext is the number of unchanged lines printed around each change.
+const diffContext = 3
+
+type diffLine struct {
+	// op is one of ' ', '-' and '+'
+	op   byte
+	text string
+}
+
+// printDiff prints the changes from before to after as a unified diff.
+func printDiff(w io.Writer, name string, before, after []byte) {
+	lines := diffLines(splitLines(before), splitLines(after))
+
+	var changes []int
+
+	for i, l := range lines {
+		if l.op != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", name, name)
+
+	// oldLine and newLine are the line numbers of lines[i] in before and after
+	oldLine, newLine := make([]int, len(lines)), make([]int, len(lines))
+
+	for i, o, n := 0, 1, 1; i < len(lines); i++ {
+		oldLine[i], newLine[i] = o, n
+
+		if lines[i].op != '+' {
+			o++
+		}
+
+		if lines[i].op != '-' {
+			n++
+		}
+	}
+
+	for i := 0; i < len(changes); {
+		start := max(changes[i]-diffContext, 0)
+		end := min(changes[i]+diffContext+1, len(lines))
+
+		// Merge the changes whose contexts overlap into a hunk
+		for i++; i < len(changes) && changes[i]-diffContext <= end; i++ {
+			end = min(changes[i]+diffContext+1, len(lines))
+		}
+
+		var oldCount, newCount int
+
+		for _, l := range lines[start:end] {
+			if l.op != '+' {
+				oldCount++
+			}
+
+			if l.op != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", oldLine[start], oldCount, newLine[start], newCount)
+
+		for _, l := range lines[start:end] {
+			fmt.Fprintf(w, "%c%s\n", l.op, l.text)
+		}
+	}
+}
+
+func splitLines(data []byte) []string {
+	s := strings.TrimSuffix(string(data), "\n")
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, "\n")
+}
+
+// diffLines returns the shortest edit from a to b, based on their longest common subsequence.
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+
+	i, j := 0, 0
+
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+
+	return lines
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
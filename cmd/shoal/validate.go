@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mumoshu/shoal"
+)
+
+func (c *cli) validate(args []string) {
+	validateFlags := flag.NewFlagSet("validate", flag.ExitOnError)
+
+	var resolve bool
+
+	validateFlags.BoolVar(&resolve, "resolve", false, "Also check that every food exists in its rig and has a version matching the constraint")
+
+	validateFlags.Parse(args)
+
+	config := c.loadConfig()
+
+	if resolve {
+		app := c.newReadOnlyApp(config, shoal.KeepGoing(true))
+
+		// Report all the misspelled food names at once, before resolving the dependencies
+		if err := app.ValidateFoods(*config); err != nil {
+			c.exit(err)
+		}
+
+		if _, err := app.Plan(*config); err != nil {
+			c.exit(err)
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "%s is valid\n", c.configFile)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mumoshu/shoal"
+)
+
+func (c *cli) versions(args []string) {
+	versionsFlags := flag.NewFlagSet("versions", flag.ExitOnError)
+
+	var rig, constraint, format string
+
+	versionsFlags.StringVar(&rig, "rig", "", "Rig to read the food from. Defaults to the rig of the dependency in the config, or the top-level rig")
+	versionsFlags.StringVar(&constraint, "constraint", "", "Only list the versions satisfying the semver constraint, like \">= 3.3.0\"")
+	versionsFlags.StringVar(&format, "format", "text", "Output format. Either text or json")
+
+	versionsFlags.Parse(args)
+
+	if versionsFlags.NArg() != 1 {
+		c.fatalf("Usage: shoal versions [--rig RIG] [--constraint CONSTRAINT] [--format text|json] FOOD")
+	}
+
+	if format != "text" && format != "json" {
+		c.fatalf("Unknown format %q: must be either text or json", format)
+	}
+
+	food := versionsFlags.Arg(0)
+
+	config := c.loadConfig()
+
+	if rig == "" {
+		rig = rigOf(config, food)
+	}
+
+	if rig == "" {
+		c.fatalf("No rig found for %s: specify --rig or set rig in %s", food, c.configFile)
+	}
+
+	app := c.newReadOnlyApp(config)
+
+	versions, err := app.ListVersions(rig, food)
+	if err != nil {
+		c.exit(err)
+	}
+
+	if constraint != "" {
+		versions, err = shoal.MatchingVersions(versions, constraint)
+		if err != nil {
+			c.fatalf("Error: %v", err)
+		}
+	}
+
+	if format == "json" {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+
+		if versions == nil {
+			versions = []shoal.FoodVersion{}
+		}
+
+		if err := e.Encode(versions); err != nil {
+			c.fatalf("Error encoding versions: %v", err)
+		}
+
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(w, "VERSION\tCOMMIT\tDATE\tPACKAGES\tDESCRIPTION")
+
+	for _, v := range versions {
+		var platforms []string
+
+		for _, p := range v.Packages {
+			platforms = append(platforms, p.String())
+		}
+
+		var date string
+
+		// The date is unknown when the git client can't log dates
+		if !v.Date.IsZero() {
+			date = v.Date.Format("2006-01-02")
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", v.Version, shortCommitID(v.FoodCommitID), dash(date), dash(strings.Join(platforms, ",")), v.Description)
+	}
+
+	w.Flush()
+}
+
+// rigOf returns the rig of the dependency on the food declared in the config, defaulting to the top-level rig.
+func rigOf(config *shoal.Config, food string) string {
+	for _, d := range config.Dependencies {
+		if d.Food == food && d.Rig != "" {
+			return d.Rig
+		}
+	}
+
+	return config.Rig
+}
@@ -0,0 +1,435 @@
+package shoal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseConfig decodes the YAML config and validates it.
+// Unlike a plain yaml.Unmarshal, unknown keys like a misspelled `dependecies` are rejected.
+// The returned error is an ErrInvalidConfig containing the line number of every problem found.
+//
+// ParseConfig doesn't process `include` nor expand environment variables and templates.
+// Use LoadConfig to read a config file along with the files it includes.
+// A config with includes is validated as a partial config, as the included files may declare e.g. the `rig`.
+func ParseConfig(data []byte) (*Config, error) {
+	return parseConfig(data, "", true)
+}
+
+// parseConfig decodes and validates the config read from file. file is used only in error messages.
+// When complete is false, or the config includes other files, checks that need the whole config are skipped.
+func parseConfig(data []byte, file string, complete bool) (*Config, error) {
+	var root yaml.Node
+
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, inFile(yamlError(err), file)
+	}
+
+	var config Config
+
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	d.KnownFields(true)
+
+	if err := d.Decode(&config); err != nil && err != io.EOF {
+		return nil, inFile(yamlError(err), file)
+	}
+
+	if errs := config.validate(complete && len(config.Include) == 0); len(errs) > 0 {
+		for i, e := range errs {
+			// Point to the nearest parent for missing fields, like the dependency without `food`
+			for j := len(e.path); j > 0; j-- {
+				if n := nodeAt(&root, e.path[:j]...); n != nil {
+					errs[i].Line = n.Line
+					break
+				}
+			}
+		}
+
+		return nil, inFile(ErrInvalidConfig{Errors: errs}, file)
+	}
+
+	return &config, nil
+}
+
+// ErrInvalidConfig is returned when the config can't be decoded or is semantically invalid.
+type ErrInvalidConfig struct {
+	Errors []ConfigError
+}
+
+func (e ErrInvalidConfig) Error() string {
+	var b strings.Builder
+
+	b.WriteString("invalid config:")
+
+	for _, c := range e.Errors {
+		fmt.Fprintf(&b, "\n  %v", c)
+	}
+
+	return b.String()
+}
+
+// ConfigError is a problem found in the config.
+type ConfigError struct {
+	// File is the config file containing the error. Empty when the config isn't read from a file.
+	File string
+	// Line is the line number in the config file, or 0 when unknown.
+	Line int
+	// Path is the path to the invalid field, like `dependencies[0].version`. Empty for syntax errors.
+	Path    string
+	Message string
+
+	path []interface{}
+}
+
+func (e ConfigError) Error() string {
+	var b strings.Builder
+
+	if e.File != "" {
+		fmt.Fprintf(&b, "%s: ", e.File)
+	}
+
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", e.Line)
+	}
+
+	if e.Path != "" {
+		fmt.Fprintf(&b, "%s: ", e.Path)
+	}
+
+	b.WriteString(e.Message)
+
+	return b.String()
+}
+
+// inFile sets the file of the ErrInvalidConfig errors.
+func inFile(err error, file string) error {
+	invalid, ok := err.(ErrInvalidConfig)
+	if !ok || file == "" {
+		return err
+	}
+
+	for i := range invalid.Errors {
+		invalid.Errors[i].File = file
+	}
+
+	return invalid
+}
+
+var yamlErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+func yamlError(err error) error {
+	var errs []ConfigError
+
+	msgs := []string{err.Error()}
+
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		msgs = typeErr.Errors
+	}
+
+	for _, msg := range msgs {
+		msg = strings.TrimPrefix(msg, "yaml: ")
+
+		var line int
+
+		if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			msg = m[2]
+		}
+
+		errs = append(errs, ConfigError{Line: line, Message: msg})
+	}
+
+	return ErrInvalidConfig{Errors: errs}
+}
+
+// Validate checks the config for semantic errors, like invalid semver constraints and dependencies without rigs.
+func (c Config) Validate() error {
+	if errs := c.validate(true); len(errs) > 0 {
+		return ErrInvalidConfig{Errors: errs}
+	}
+
+	return nil
+}
+
+// validate returns the semantic errors in the config.
+// When complete is false, the config is a part of the merged config, and the checks that need the whole config are skipped.
+func (c Config) validate(complete bool) []ConfigError {
+	var errs []ConfigError
+
+	add := func(msg string, path ...interface{}) {
+		errs = append(errs, ConfigError{Path: formatPath(path), Message: msg, path: path})
+	}
+
+	for i, inc := range c.Include {
+		if inc.Path == "" {
+			add("path is required", "include", i, "path")
+		}
+
+		if inc.Ref != "" && inc.Git == "" {
+			add("ref requires git", "include", i, "ref")
+		}
+	}
+
+	for i, m := range c.Mirrors {
+		if m.Prefix == "" {
+			add("prefix is required", "mirrors", i, "prefix")
+		}
+
+		if m.URL == "" {
+			add("url is required", "mirrors", i, "url")
+		}
+	}
+
+	if c.HTTP.Proxy != "" {
+		if _, err := url.Parse(c.HTTP.Proxy); err != nil {
+			add(fmt.Sprintf("invalid proxy URL: %v", err), "http", "proxy")
+		}
+	}
+
+	for _, r := range []struct {
+		section string
+		policy  RetryPolicy
+	}{
+		{"git", c.Git.Retry},
+		{"download", c.Download.Retry},
+	} {
+		if r.policy.Attempts < 0 {
+			add("attempts must not be negative", r.section, "retry", "attempts")
+		}
+
+		if r.policy.Backoff < 0 {
+			add("backoff must not be negative", r.section, "retry", "backoff")
+		}
+
+		if r.policy.MaxBackoff < 0 {
+			add("maxBackoff must not be negative", r.section, "retry", "maxBackoff")
+		}
+	}
+
+	if c.Git.Timeout < 0 {
+		add("timeout must not be negative", "git", "timeout")
+	}
+
+	if c.Download.Timeout < 0 {
+		add("timeout must not be negative", "download", "timeout")
+	}
+
+	switch c.Git.Provider {
+	case "", "native", "go-git":
+	default:
+		add(fmt.Sprintf("unknown git provider %q: must be either native or go-git", c.Git.Provider), "git", "provider")
+	}
+
+	foods := c.Foods.versions()
+
+	for _, name := range sortedKeys(foods) {
+		if err := validateConstraint(foods[name]); err != nil {
+			add(err.Error(), "foods", name)
+		}
+	}
+
+	if complete && len(foods) > 0 && c.Rig == "" {
+		add("rig is required when foods are declared", "rig")
+	}
+
+	for i, d := range c.Dependencies {
+		if d.Rig == "" {
+			add("rig is required", "dependencies", i, "rig")
+		}
+
+		if d.Food == "" {
+			add("food is required", "dependencies", i, "food")
+		}
+
+		if err := validateConstraint(d.Version); err != nil {
+			add(err.Error(), "dependencies", i, "version")
+		}
+
+		var platforms []string
+
+		for p := range d.Versions {
+			platforms = append(platforms, p)
+		}
+
+		sort.Strings(platforms)
+
+		for _, p := range platforms {
+			if ps := strings.Split(p, "/"); len(ps) > 2 || ps[0] == "" || ps[len(ps)-1] == "" {
+				add(fmt.Sprintf("invalid platform %q: must be either OS or OS/arch", p), "dependencies", i, "versions", p)
+			}
+
+			if err := validateConstraint(d.Versions[p]); err != nil {
+				add(err.Error(), "dependencies", i, "versions", p)
+			}
+		}
+	}
+
+	if err := validateConstraint(c.Helm.Plugins.Diff); err != nil {
+		add(err.Error(), "helm", "plugins", "diff")
+	}
+
+	return errs
+}
+
+// versions returns the versions of the declared foods by name.
+func (f Foods) versions() map[string]string {
+	versions := map[string]string{}
+
+	for name, v := range map[string]string{
+		"helm":     f.Helm,
+		"helmfile": f.Helmfile,
+		"kubectl":  f.Kubectl,
+		"eksctl":   f.Eksctl,
+	} {
+		if v != "" {
+			versions[name] = v
+		}
+	}
+
+	for name, v := range f.Others {
+		if v != "" {
+			versions[name] = v
+		}
+	}
+
+	return versions
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ValidateFoods checks that every food declared under `foods` is found in the history of the rig.
+// The strict decoding can't reject a misspelled food name, as any key under `foods` is taken as a food name.
+// The returned error is an ErrInvalidConfig listing all the unknown foods.
+func (a *App) ValidateFoods(config Config) error {
+	a.setEnv()
+
+	foods := config.Foods.versions()
+
+	if config.Rig == "" || len(foods) == 0 {
+		return nil
+	}
+
+	workspaceDir, err := a.workspace(config.Rig)
+	if err != nil {
+		return err
+	}
+
+	var errs []ConfigError
+
+	for _, name := range sortedKeys(foods) {
+		out, err := a.git.Log(workspaceDir, filepath.Join("Food", fmt.Sprintf("%s.lua", name)))
+		if err != nil {
+			return withRig(err, config.Rig)
+		}
+
+		if strings.TrimSpace(out) == "" {
+			errs = append(errs, ConfigError{
+				Path:    formatPath([]interface{}{"foods", name}),
+				Message: fmt.Sprintf("unknown food: no food named %q found in rig %q", name, config.Rig),
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return ErrInvalidConfig{Errors: errs}
+	}
+
+	return nil
+}
+
+func validateConstraint(c string) error {
+	if c == "" {
+		return nil
+	}
+
+	if _, err := semver.NewConstraint(c); err != nil {
+		return fmt.Errorf("invalid semver constraint %q: %v", c, err)
+	}
+
+	return nil
+}
+
+func formatPath(path []interface{}) string {
+	var b strings.Builder
+
+	for _, p := range path {
+		switch v := p.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", v)
+		default:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			fmt.Fprintf(&b, "%v", v)
+		}
+	}
+
+	return b.String()
+}
+
+// nodeAt returns the node at the path made of mapping keys and sequence indices,
+// or nil when there is no such node.
+// For a mapping key, the key node is returned so that the line points to the field name.
+func nodeAt(n *yaml.Node, path ...interface{}) *yaml.Node {
+	if n.Kind == yaml.DocumentNode {
+		if len(n.Content) == 0 {
+			return nil
+		}
+		n = n.Content[0]
+	}
+
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	if len(path) == 0 {
+		return n
+	}
+
+	switch p := path[0].(type) {
+	case int:
+		if n.Kind != yaml.SequenceNode || p >= len(n.Content) {
+			return nil
+		}
+
+		return nodeAt(n.Content[p], path[1:]...)
+	case string:
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value != p {
+				continue
+			}
+
+			if len(path) == 1 {
+				return n.Content[i]
+			}
+
+			return nodeAt(n.Content[i+1], path[1:]...)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package shoal
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	testcases := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "misspelled key",
+			yaml: "rig: &rig https://github.com/fishworks/fish-food\ndependecies:\n- rig: *rig\n",
+			want: []string{"line 2: field dependecies not found in type shoal.Config"},
+		},
+		{
+			name: "misspelled dependency key",
+			yaml: "dependencies:\n- rig: r\n  food: helm\n  verison: 3.3.0\n",
+			want: []string{"line 4: field verison not found in type shoal.Dependency"},
+		},
+		{
+			name: "semantic errors",
+			yaml: "git:\n  provider: gogit\ndependencies:\n- rig: r\n  version: \">== 1\"\n",
+			want: []string{
+				`line 2: git.provider: unknown git provider "gogit": must be either native or go-git`,
+				`line 4: dependencies[0].food: food is required`,
+				`line 5: dependencies[0].version: invalid semver constraint ">== 1": improper constraint: >== 1`,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tc.yaml))
+
+			var invalid ErrInvalidConfig
+
+			if !errors.As(err, &invalid) {
+				t.Fatalf("want ErrInvalidConfig, got %v", err)
+			}
+
+			if len(invalid.Errors) != len(tc.want) {
+				t.Fatalf("want %d errors, got %v", len(tc.want), err)
+			}
+
+			for i, e := range invalid.Errors {
+				if e.Error() != tc.want[i] {
+					t.Errorf("error %d: want %q, got %q", i, tc.want[i], e.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestParseConfigExamples(t *testing.T) {
+	for _, f := range []string{"example.yaml", "examples/cli/shoal.yaml"} {
+		bs, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatalf("reading %s: %v", f, err)
+		}
+
+		if _, err := ParseConfig(bs); err != nil {
+			t.Errorf("parsing %s: %v", f, err)
+		}
+	}
+}
+
+func TestLoadConfigIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shoal-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"base/tools.yaml": "rig: r\nhttp:\n  insecureSkipVerify: true\nfoods:\n  helm: \">= 3.0\"\n  kubectl: \"1.18\"\ndependencies:\n- rig: r\n  food: jq\n  version: \"1.5\"\n- rig: r\n  food: yq\n",
+		"shoal.yaml":      "include:\n- base/tools.yaml\nhttp:\n  insecureSkipVerify: false\nfoods:\n  helm: \">= 3.3\"\ndependencies:\n- rig: r\n  food: jq\n  version: \"1.6\"\n- rig: r\n  food: kubectl\n  version: \"1.19\"\n",
+	}
+
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := LoadConfig(filepath.Join(dir, "shoal.yaml"))
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+
+	want := []Dependency{
+		{Rig: "r", Food: "helm", Version: ">= 3.3"},
+		{Rig: "r", Food: "kubectl", Version: "1.19"},
+		{Rig: "r", Food: "jq", Version: "1.6"},
+		{Rig: "r", Food: "yq"},
+	}
+
+	if got := config.dependencies(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	// The including config sets it back to false
+	if config.HTTP.insecureSkipVerify() {
+		t.Error("want insecureSkipVerify to be overridden by the including config")
+	}
+}
+
+func TestLoadConfigGitIncludes(t *testing.T) {
+	repo := testRig(t)
+	defer os.RemoveAll(repo)
+
+	commitFile(t, repo, "base/tools.yaml", "include:\n- common.yaml\ndependencies:\n- rig: r\n  food: jq\n", "base")
+	commitFile(t, repo, "base/common.yaml", "dependencies:\n- rig: r\n  food: yq\n", "common")
+
+	dir, err := ioutil.TempDir("", "shoal-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The repository is cloned through the mirror, with the retry settings of the including config
+	config := "mirrors:\n- prefix: https://git.example.com/\n  url: " + repo + "/\ngit:\n  retry:\n    attempts: 1\n" +
+		"include:\n- git: https://git.example.com/\n  path: base/tools.yaml\n- git: https://git.example.com/\n  path: base/common.yaml\n"
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "shoal.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadConfig(filepath.Join(dir, "shoal.yaml"))
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+
+	want := []Dependency{{Rig: "r", Food: "yq"}, {Rig: "r", Food: "jq"}}
+
+	if got := loaded.dependencies(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestValidateFoods(t *testing.T) {
+	packages := newTestPackages(t)
+
+	rig := testRig(t, packages.food(t, "foo", "1.0.0", "linux/amd64"))
+	defer os.RemoveAll(rig)
+
+	config := Config{Rig: rig, Foods: Foods{Helm: "3.3.0", Others: map[string]string{"foo": "1.0.0", "fooo": "1.0.0"}}}
+
+	app := testApp(t, testRoot(t), config, KeepGoing(true), Target("linux", "amd64"))
+
+	var invalid ErrInvalidConfig
+
+	if err := app.ValidateFoods(config); !errors.As(err, &invalid) {
+		t.Fatalf("want ErrInvalidConfig, got %v", err)
+	}
+
+	if len(invalid.Errors) != 2 || invalid.Errors[0].Path != "foods.fooo" || invalid.Errors[1].Path != "foods.helm" {
+		t.Errorf("want the unknown foods to be reported, got %v", invalid)
+	}
+
+	// Plan and Sync in the keep-going mode report the unknown foods along with the other failures
+	_, planErr := app.Plan(config)
+
+	for _, err := range []error{planErr, app.Sync(config)} {
+		var deps ErrDependencies
+
+		if !errors.As(err, &deps) {
+			t.Fatalf("want ErrDependencies, got %v", err)
+		}
+
+		var unknown []string
+
+		for _, d := range deps {
+			var noMatching ErrNoMatchingVersion
+
+			if errors.As(d, &noMatching) && len(noMatching.Versions) == 0 {
+				unknown = append(unknown, d.Food)
+			}
+		}
+
+		if len(deps) != 2 || len(unknown) != 2 {
+			t.Errorf("want the 2 unknown foods to fail, got %v", deps)
+		}
+	}
+
+	delete(config.Foods.Others, "fooo")
+	config.Foods.Helm = ""
+
+	if err := app.ValidateFoods(config); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
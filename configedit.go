@@ -0,0 +1,730 @@
+package shoal
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is a config file edited in place.
+// Unlike encoding the decoded config back to YAML, edits only touch the lines of the changed entries,
+// so that comments, formatting, and anchors like `&rig` are preserved.
+// Includes, environment variables and templates are neither processed nor expanded.
+type ConfigFile struct {
+	path string
+	mode os.FileMode
+	data []byte
+	// root is the top-level mapping, or nil when the file is empty.
+	root *yaml.Node
+}
+
+// OpenConfigFile reads the config file for editing.
+func OpenConfigFile(path string) (*ConfigFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &ConfigFile{path: path, mode: info.Mode().Perm()}
+
+	if err := f.setData(data); err != nil {
+		return nil, inFile(err, path)
+	}
+
+	return f, nil
+}
+
+// Bytes returns the edited content of the config file.
+func (f *ConfigFile) Bytes() []byte {
+	return f.data
+}
+
+// Save writes the edited content back to the config file.
+func (f *ConfigFile) Save() error {
+	return ioutil.WriteFile(f.path, f.data, f.mode)
+}
+
+// SetDependency sets the version constraint of the dependency on the food in the rig, adding the dependency when it isn't declared.
+// An empty rig means the top-level rig. New dependencies refer to it by its alias when it has an anchor like `&rig`.
+// An empty version removes the constraint of the declared dependency.
+//
+// The constraint is updated in place in either `dependencies` or `foods`, whichever declares the food.
+// New dependencies are appended to `dependencies`, unless the config declares the foods of the top-level rig only in `foods`.
+func (f *ConfigFile) SetDependency(rig, food, version string) error {
+	if item, end, err := f.findDependency(rig, food); err != nil {
+		return err
+	} else if item != nil {
+		return f.setDependencyVersion(item, end, version)
+	}
+
+	if key, value := f.findFood(rig, food); value != nil {
+		if version == "" {
+			return fmt.Errorf("food %q declared in foods at line %d requires a version", food, key.Line)
+		}
+
+		return f.setValue(key, value, version)
+	}
+
+	return f.addDependency(rig, food, version)
+}
+
+// RemoveDependency removes the dependencies on the food in the rig from both `dependencies` and `foods`,
+// along with the comments above them. An empty rig matches any rig.
+// It returns false when the config doesn't declare the food.
+func (f *ConfigFile) RemoveDependency(rig, food string) (bool, error) {
+	var removed bool
+
+	for {
+		item, end, err := f.findDependencyIn(rig, food, true)
+		if err != nil {
+			return removed, err
+		}
+
+		if item != nil {
+			start := f.itemStart(item)
+
+			key, deps := mappingValue(f.root, "dependencies")
+
+			if len(deps.Content) == 1 {
+				// Remove the now empty `dependencies` too
+				start = key.Line
+			} else if lines := f.lines(); deps.Content[0] == item || strings.TrimSpace(lines[start-2]) == "" {
+				// Don't leave two blank lines, or a blank line above the first item, where the item was
+				for end < len(lines) && lines[end] != "" && strings.TrimSpace(lines[end]) == "" {
+					end++
+				}
+			}
+
+			if err := f.deleteLines(start, end); err != nil {
+				return removed, err
+			}
+
+			removed = true
+
+			continue
+		}
+
+		key, value := f.findFood(rig, food)
+		if value == nil {
+			return removed, nil
+		}
+
+		start, end := key.Line, f.entryEnd(key)
+
+		if foodsKey, foods := mappingValue(f.root, "foods"); len(foods.Content) == 2 {
+			start = foodsKey.Line
+		}
+
+		if err := f.deleteLines(start, end); err != nil {
+			return removed, err
+		}
+
+		removed = true
+	}
+}
+
+func (f *ConfigFile) setData(data []byte) error {
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return yamlError(err)
+	}
+
+	var root *yaml.Node
+
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+
+		if root.Kind != yaml.MappingNode {
+			return ErrInvalidConfig{Errors: []ConfigError{{Line: root.Line, Message: "the config must be a mapping"}}}
+		}
+	}
+
+	f.data, f.root = data, root
+
+	return nil
+}
+
+// lines returns the lines of the config file, each including its line break.
+func (f *ConfigFile) lines() []string {
+	return strings.SplitAfter(string(f.data), "\n")
+}
+
+func (f *ConfigFile) setLines(lines []string) error {
+	return f.setData([]byte(strings.Join(lines, "")))
+}
+
+// deleteLines deletes the lines from start to end, both 1-based and inclusive.
+func (f *ConfigFile) deleteLines(start, end int) error {
+	lines := f.lines()
+
+	return f.setLines(append(lines[:start-1:start-1], lines[end:]...))
+}
+
+// insertLines inserts the lines after the line numbered after. Zero inserts them at the beginning.
+func (f *ConfigFile) insertLines(after int, text ...string) error {
+	lines := f.lines()
+
+	var inserted []string
+
+	for _, t := range text {
+		inserted = append(inserted, t+"\n")
+	}
+
+	if after > 0 && !strings.HasSuffix(lines[after-1], "\n") {
+		lines[after-1] += "\n"
+	}
+
+	return f.setLines(append(lines[:after:after], append(inserted, lines[after:]...)...))
+}
+
+// lastLine returns the number of the last line of the first document, not counting the empty one after the trailing line break.
+// Only the first document of a file with multiple documents is read as the config, and edited.
+func (f *ConfigFile) lastLine() int {
+	lines := f.lines()
+
+	last := len(lines)
+
+	if lines[last-1] == "" {
+		last--
+	}
+
+	start, started := 1, f.root != nil
+
+	if f.root != nil {
+		start = f.root.Line + 1
+	}
+
+	for i := start; i <= last; i++ {
+		if !isDocumentMarker(lines[i-1]) {
+			continue
+		}
+
+		if !started {
+			// The `---` starting the empty first document
+			started = true
+			continue
+		}
+
+		return i - 1
+	}
+
+	return last
+}
+
+// contentEnd returns the last line from start to limit that is neither blank nor a comment.
+// Comments and blank lines at the end are usually about what follows.
+func (f *ConfigFile) contentEnd(start, limit int) int {
+	lines := f.lines()
+
+	for i := limit; i > start; i-- {
+		if l := strings.TrimSpace(lines[i-1]); l != "" && !strings.HasPrefix(l, "#") {
+			return i
+		}
+	}
+
+	return start
+}
+
+// sectionEnd returns the last content line of the value of the top-level key.
+func (f *ConfigFile) sectionEnd(key *yaml.Node) int {
+	limit := f.lastLine()
+
+	for i := 0; i < len(f.root.Content); i += 2 {
+		if f.root.Content[i] == key && i+2 < len(f.root.Content) {
+			limit = f.root.Content[i+2].Line - 1
+		}
+	}
+
+	return f.contentEnd(key.Line, limit)
+}
+
+// itemStart returns the line of the dash of the sequence item, or of the first comment above it.
+func (f *ConfigFile) itemStart(item *yaml.Node) int {
+	lines := f.lines()
+
+	start := item.Line
+
+	if strings.TrimSpace(lines[start-1][:byteOffset(lines[start-1], item.Column)]) == "" {
+		// The dash is on its own line
+		start--
+	}
+
+	if item.HeadComment != "" {
+		for n := strings.Count(item.HeadComment, "\n") + 1; n > 0 && start > 1; n-- {
+			if !strings.HasPrefix(strings.TrimSpace(lines[start-2]), "#") {
+				break
+			}
+
+			start--
+		}
+	}
+
+	return start
+}
+
+func (f *ConfigFile) findDependency(rig, food string) (*yaml.Node, int, error) {
+	return f.findDependencyIn(rig, food, false)
+}
+
+// findDependencyIn returns the first item in `dependencies` on the food in the rig, along with its last content line.
+// anyRig makes an empty rig match any rig, instead of the top-level rig.
+func (f *ConfigFile) findDependencyIn(rig, food string, anyRig bool) (*yaml.Node, int, error) {
+	key, deps := mappingValue(f.root, "dependencies")
+	if deps == nil || deps.Kind != yaml.SequenceNode {
+		return nil, 0, nil
+	}
+
+	for i, item := range deps.Content {
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+
+		_, foodNode := mappingValue(item, "food")
+		if foodNode == nil || foodNode.Value != food {
+			continue
+		}
+
+		_, rigNode := mappingValue(item, "rig")
+		if !(anyRig && rig == "") && !f.isRig(rigNode, rig) {
+			continue
+		}
+
+		if deps.Style&yaml.FlowStyle != 0 || item.Style&yaml.FlowStyle != 0 {
+			return nil, 0, fmt.Errorf("dependency on food %q at line %d is in flow style, which can't be edited", food, item.Line)
+		}
+
+		end := f.sectionEnd(key)
+
+		if i+1 < len(deps.Content) {
+			end = f.contentEnd(item.Line, f.itemStart(deps.Content[i+1])-1)
+		}
+
+		return item, end, nil
+	}
+
+	return nil, 0, nil
+}
+
+// findFood returns the key and the value of the food in `foods`, whose foods are all in the top-level rig.
+func (f *ConfigFile) findFood(rig, food string) (*yaml.Node, *yaml.Node) {
+	if rig != "" {
+		if _, topRig := mappingValue(f.root, "rig"); topRig == nil || topRig.Value != rig {
+			return nil, nil
+		}
+	}
+
+	_, foods := mappingValue(f.root, "foods")
+	if foods == nil || foods.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	return mappingValue(foods, food)
+}
+
+// declaredDependency is a dependency with a version constraint as written in the config file,
+// before the environment variables and templates are expanded.
+type declaredDependency struct {
+	rig, food, version string
+	// key and node are the key and the value of the version constraint.
+	key, node *yaml.Node
+}
+
+// declaredDependencies returns the dependencies with version constraints in both `dependencies` and `foods`,
+// in the order they appear in the file.
+func (f *ConfigFile) declaredDependencies() []declaredDependency {
+	var deps []declaredDependency
+
+	_, topRig := mappingValue(f.root, "rig")
+
+	if _, foods := mappingValue(f.root, "foods"); topRig != nil && foods != nil && foods.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(foods.Content); i += 2 {
+			if v := foods.Content[i+1]; v.Kind == yaml.ScalarNode && v.Value != "" {
+				deps = append(deps, declaredDependency{rig: topRig.Value, food: foods.Content[i].Value, version: v.Value, key: foods.Content[i], node: v})
+			}
+		}
+	}
+
+	if _, items := mappingValue(f.root, "dependencies"); items != nil && items.Kind == yaml.SequenceNode {
+		for _, item := range items.Content {
+			_, rigNode := mappingValue(item, "rig")
+			_, foodNode := mappingValue(item, "food")
+			k, v := mappingValue(item, "version")
+
+			if rigNode == nil || foodNode == nil || v == nil || v.Kind != yaml.ScalarNode || v.Value == "" {
+				continue
+			}
+
+			if rigNode.Kind == yaml.AliasNode {
+				rigNode = rigNode.Alias
+			}
+
+			deps = append(deps, declaredDependency{rig: rigNode.Value, food: foodNode.Value, version: v.Value, key: k, node: v})
+		}
+	}
+
+	sort.SliceStable(deps, func(i, j int) bool {
+		return deps[i].node.Line < deps[j].node.Line
+	})
+
+	return deps
+}
+
+// setConstraint replaces the version constraint at the line, which is one of the declared dependencies.
+func (f *ConfigFile) setConstraint(line int, version string) error {
+	for _, d := range f.declaredDependencies() {
+		if d.node.Line == line {
+			return f.setValue(d.key, d.node, version)
+		}
+	}
+
+	return fmt.Errorf("no version constraint found at line %d", line)
+}
+
+// isRig returns true when the rig node of a dependency refers to the rig. An empty rig means the top-level rig.
+func (f *ConfigFile) isRig(n *yaml.Node, rig string) bool {
+	if n == nil {
+		return false
+	}
+
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	if rig != "" {
+		return n.Value == rig
+	}
+
+	_, topRig := mappingValue(f.root, "rig")
+
+	return topRig != nil && (n == topRig || n.Value == topRig.Value)
+}
+
+func (f *ConfigFile) setDependencyVersion(item *yaml.Node, end int, version string) error {
+	key, value := mappingValue(item, "version")
+
+	switch {
+	case value != nil && version != "":
+		return f.setValue(key, value, version)
+	case value != nil:
+		return f.deleteLines(key.Line, f.entryEnd(key))
+	case version != "":
+		return f.insertLines(end, indent(item.Column)+"version: "+quoteScalar(version, 0))
+	}
+
+	return nil
+}
+
+func (f *ConfigFile) addDependency(rig, food, version string) error {
+	rigText, err := f.rigText(rig)
+	if err != nil {
+		return err
+	}
+
+	depsKey, deps := mappingValue(f.root, "dependencies")
+
+	if foodsKey, foods := mappingValue(f.root, "foods"); rig == "" && deps == nil && version != "" &&
+		foods != nil && foods.Kind == yaml.MappingNode && foods.Style&yaml.FlowStyle == 0 && len(foods.Content) > 0 {
+		last := foods.Content[len(foods.Content)-2]
+
+		return f.insertLines(f.sectionEnd(foodsKey), indent(last.Column)+quoteScalar(food, 0)+": "+quoteScalar(version, 0))
+	}
+
+	entry := func(prefix string) []string {
+		lines := []string{
+			prefix + "rig: " + rigText,
+			indent(len(prefix)+1) + "food: " + quoteScalar(food, 0),
+		}
+
+		if version != "" {
+			lines = append(lines, indent(len(prefix)+1)+"version: "+quoteScalar(version, 0))
+		}
+
+		return lines
+	}
+
+	switch {
+	case deps == nil:
+		return f.insertLines(f.lastLine(), append([]string{"dependencies:"}, entry("- ")...)...)
+	case deps.Kind == yaml.SequenceNode && deps.Style&yaml.FlowStyle == 0 && len(deps.Content) > 0:
+		lines := f.lines()
+		last := deps.Content[len(deps.Content)-1]
+		prefix := lines[last.Line-1][:byteOffset(lines[last.Line-1], last.Column)]
+
+		if strings.TrimSpace(prefix) != "-" {
+			// The dash is on its own line, or the item isn't a mapping
+			prefix = indent(f.itemColumn(last)) + "- "
+		}
+
+		return f.insertLines(f.sectionEnd(depsKey), entry(prefix)...)
+	case deps.Tag == "!!null" || deps.Kind == yaml.SequenceNode && len(deps.Content) == 0:
+		if deps.Line == depsKey.Line && (deps.Value != "" || deps.Kind == yaml.SequenceNode) {
+			// Drop the explicit null or [] from `dependencies:`
+			if err := f.replaceSpan(deps, ""); err != nil {
+				return err
+			}
+		}
+
+		return f.insertLines(depsKey.Line, entry(indent(depsKey.Column)+"- ")...)
+	}
+
+	return fmt.Errorf("dependencies at line %d must be a block sequence to add the dependency on food %q", deps.Line, food)
+}
+
+// itemColumn returns the column of the dash of the sequence item.
+func (f *ConfigFile) itemColumn(item *yaml.Node) int {
+	lines := f.lines()
+	line := lines[f.itemStart(item)-1]
+
+	return len(line) - len(strings.TrimLeft(line, " ")) + 1
+}
+
+// rigText returns the YAML text to refer to the rig in a new dependency.
+func (f *ConfigFile) rigText(rig string) (string, error) {
+	_, topRig := mappingValue(f.root, "rig")
+
+	if rig == "" {
+		if topRig == nil {
+			return "", errors.New("no top-level rig is declared in the config")
+		}
+
+		if topRig.Anchor != "" {
+			return "*" + topRig.Anchor, nil
+		}
+
+		return f.scalarText(topRig)
+	}
+
+	if topRig != nil && topRig.Anchor != "" && topRig.Value == rig {
+		return "*" + topRig.Anchor, nil
+	}
+
+	return quoteScalar(rig, 0), nil
+}
+
+// entryEnd returns the last line of the mapping entry, whose value spans the lines indented more than its key,
+// like a folded scalar.
+func (f *ConfigFile) entryEnd(key *yaml.Node) int {
+	lines := f.lines()
+
+	end := key.Line
+
+	for i := key.Line + 1; i <= len(lines); i++ {
+		l := strings.TrimRight(lines[i-1], "\r\n")
+
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+
+		if len(l)-len(strings.TrimLeft(l, " ")) < key.Column {
+			break
+		}
+
+		end = i
+	}
+
+	return end
+}
+
+// setValue replaces the scalar value of the mapping entry. A value spanning multiple lines, like a folded scalar,
+// is replaced with a single line.
+func (f *ConfigFile) setValue(key, value *yaml.Node, text string) error {
+	end := f.entryEnd(key)
+
+	if end == key.Line && value.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+		return f.replaceScalar(value, text)
+	}
+
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("value at line %d must be a string", value.Line)
+	}
+
+	line := f.lines()[key.Line-1]
+
+	if err := f.deleteLines(key.Line, end); err != nil {
+		return err
+	}
+
+	return f.insertLines(key.Line-1, line[:byteOffset(line, key.Column)]+quoteScalar(key.Value, 0)+": "+quoteScalar(text, 0))
+}
+
+// replaceScalar replaces the value of the scalar, keeping it double-quoted or single-quoted when it was.
+func (f *ConfigFile) replaceScalar(n *yaml.Node, value string) error {
+	if n.Kind != yaml.ScalarNode {
+		return fmt.Errorf("value at line %d must be a string", n.Line)
+	}
+
+	return f.replaceSpan(n, quoteScalar(value, n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle)))
+}
+
+// replaceSpan replaces the text of the scalar or the empty flow sequence on a single line, keeping its anchor and tag.
+func (f *ConfigFile) replaceSpan(n *yaml.Node, text string) error {
+	start, end, err := f.span(n)
+	if err != nil {
+		return err
+	}
+
+	lines := f.lines()
+	line := lines[n.Line-1]
+	rest := line[end:]
+
+	if text == "" {
+		// Drop the space before the removed value, as in `dependencies: []`
+		for start > 0 && line[start-1] == ' ' {
+			start--
+		}
+	}
+
+	lines[n.Line-1] = line[:start] + text + rest
+
+	return f.setLines(lines)
+}
+
+func (f *ConfigFile) scalarText(n *yaml.Node) (string, error) {
+	start, end, err := f.span(n)
+	if err != nil {
+		return "", err
+	}
+
+	return f.lines()[n.Line-1][start:end], nil
+}
+
+// span returns the byte offsets of the start and the end of the node in its line, excluding its anchor and tag.
+func (f *ConfigFile) span(n *yaml.Node) (int, int, error) {
+	line := strings.TrimRight(f.lines()[n.Line-1], "\r\n")
+	start := byteOffset(line, n.Column)
+
+	skipToken := func(prefix string) {
+		if strings.HasPrefix(line[start:], prefix) {
+			for start < len(line) && line[start] != ' ' {
+				start++
+			}
+
+			for start < len(line) && line[start] == ' ' {
+				start++
+			}
+		}
+	}
+
+	skipToken("&")
+	skipToken("!")
+
+	multiLine := fmt.Errorf("value at line %d spans multiple lines, which can't be edited", n.Line)
+
+	switch {
+	case n.Kind == yaml.SequenceNode && n.Style&yaml.FlowStyle != 0 && len(n.Content) == 0:
+		if end := strings.Index(line[start:], "]"); end >= 0 {
+			return start, start + end + 1, nil
+		}
+
+		return 0, 0, multiLine
+	case n.Kind != yaml.ScalarNode:
+		return 0, 0, fmt.Errorf("value at line %d must be a string", n.Line)
+	case n.Style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				i++
+			case '"':
+				return start, i + 1, nil
+			}
+		}
+
+		return 0, 0, multiLine
+	case n.Style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(line); i++ {
+			if line[i] == '\'' {
+				if i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					continue
+				}
+
+				return start, i + 1, nil
+			}
+		}
+
+		return 0, 0, multiLine
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return 0, 0, multiLine
+	}
+
+	if end := start + len(n.Value); strings.HasPrefix(line[start:], n.Value) {
+		return start, end, nil
+	}
+
+	if n.Value == "" || n.Tag == "!!null" {
+		// An explicit null like `~`
+		end := start
+
+		for end < len(line) && line[end] != ' ' && line[end] != '#' {
+			end++
+		}
+
+		return start, end, nil
+	}
+
+	return 0, 0, multiLine
+}
+
+// mappingValue returns the key and the value of the mapping node for the key, or nils when the key isn't found.
+func mappingValue(m *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i], m.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
+
+// isDocumentMarker returns true when the line starts or ends a YAML document, like `---` and `...`.
+func isDocumentMarker(line string) bool {
+	if !strings.HasPrefix(line, "---") && !strings.HasPrefix(line, "...") {
+		return false
+	}
+
+	return len(line) == 3 || strings.ContainsAny(line[3:4], " \t\r\n")
+}
+
+// quoteScalar returns the string as a YAML scalar in the style. The zero style quotes it only when it would otherwise be read
+// as another value, like `'>= 3.3'` and `"1.0"`.
+func quoteScalar(s string, style yaml.Style) string {
+	out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s, Style: style})
+	if err != nil {
+		return fmt.Sprintf("%q", s)
+	}
+
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// indent returns the spaces to indent a line to the 1-based column.
+func indent(column int) string {
+	return strings.Repeat(" ", column-1)
+}
+
+// byteOffset returns the byte offset of the 1-based column of the line, counted in characters.
+func byteOffset(line string, column int) int {
+	offset := 0
+
+	for i := 1; i < column && offset < len(line); i++ {
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+
+	return offset
+}
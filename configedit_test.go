@@ -0,0 +1,337 @@
+package shoal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const editedConfig = `# The rig all the foods are read from
+rig: &rig https://github.com/fishworks/fish-food
+
+dependencies:
+# helm is used by helmfile
+- rig: *rig
+  food: helm
+  version: ">= 3.3.0" # keep in sync with CI
+
+- rig: https://github.com/example/rig
+  food: kubectl
+  vars:
+    variant: fips
+
+# Tools for the e2e tests
+helm:
+  plugins:
+    diff: 3.1.3
+`
+
+func TestConfigFileSetDependency(t *testing.T) {
+	testcases := []struct {
+		name               string
+		yaml               string
+		rig, food, version string
+		want               string
+	}{
+		{
+			name:    "update version",
+			yaml:    editedConfig,
+			food:    "helm",
+			version: ">= 3.4.0",
+			want: `# The rig all the foods are read from
+rig: &rig https://github.com/fishworks/fish-food
+
+dependencies:
+# helm is used by helmfile
+- rig: *rig
+  food: helm
+  version: ">= 3.4.0" # keep in sync with CI
+
+- rig: https://github.com/example/rig
+  food: kubectl
+  vars:
+    variant: fips
+
+# Tools for the e2e tests
+helm:
+  plugins:
+    diff: 3.1.3
+`,
+		},
+		{
+			name:    "add version",
+			yaml:    editedConfig,
+			rig:     "https://github.com/example/rig",
+			food:    "kubectl",
+			version: "1.19.0",
+			want: `# The rig all the foods are read from
+rig: &rig https://github.com/fishworks/fish-food
+
+dependencies:
+# helm is used by helmfile
+- rig: *rig
+  food: helm
+  version: ">= 3.3.0" # keep in sync with CI
+
+- rig: https://github.com/example/rig
+  food: kubectl
+  vars:
+    variant: fips
+  version: 1.19.0
+
+# Tools for the e2e tests
+helm:
+  plugins:
+    diff: 3.1.3
+`,
+		},
+		{
+			name:    "add dependency with alias",
+			yaml:    editedConfig,
+			food:    "helmfile",
+			version: "0.125.0",
+			want: `# The rig all the foods are read from
+rig: &rig https://github.com/fishworks/fish-food
+
+dependencies:
+# helm is used by helmfile
+- rig: *rig
+  food: helm
+  version: ">= 3.3.0" # keep in sync with CI
+
+- rig: https://github.com/example/rig
+  food: kubectl
+  vars:
+    variant: fips
+- rig: *rig
+  food: helmfile
+  version: 0.125.0
+
+# Tools for the e2e tests
+helm:
+  plugins:
+    diff: 3.1.3
+`,
+		},
+		{
+			name: "add dependency in another rig",
+			yaml: "rig: https://github.com/fishworks/fish-food\ndependencies:\n  - rig: https://github.com/fishworks/fish-food\n    food: helm\n",
+			rig:  "https://github.com/example/rig",
+			food: "kubectl",
+			want: "rig: https://github.com/fishworks/fish-food\ndependencies:\n  - rig: https://github.com/fishworks/fish-food\n    food: helm\n  - rig: https://github.com/example/rig\n    food: kubectl\n",
+		},
+		{
+			name:    "add to foods",
+			yaml:    "rig: https://github.com/fishworks/fish-food # default\nfoods:\n  helm: \">= 3.3.0\"\n\n# e2e\nhelm: {}\n",
+			food:    "eksctl",
+			version: ">= 0.27.0",
+			want:    "rig: https://github.com/fishworks/fish-food # default\nfoods:\n  helm: \">= 3.3.0\"\n  eksctl: '>= 0.27.0'\n\n# e2e\nhelm: {}\n",
+		},
+		{
+			name:    "update foods",
+			yaml:    "rig: https://github.com/fishworks/fish-food\nfoods:\n  helm: \">= 3.3.0\"\n  kubectl: 1.18.0\n",
+			rig:     "https://github.com/fishworks/fish-food",
+			food:    "kubectl",
+			version: "1.19.0",
+			want:    "rig: https://github.com/fishworks/fish-food\nfoods:\n  helm: \">= 3.3.0\"\n  kubectl: 1.19.0\n",
+		},
+		{
+			name:    "add dependencies",
+			yaml:    "rig: 'https://github.com/fishworks/fish-food'\n",
+			food:    "helm",
+			version: "3.3.4",
+			want:    "rig: 'https://github.com/fishworks/fish-food'\ndependencies:\n- rig: 'https://github.com/fishworks/fish-food'\n  food: helm\n  version: 3.3.4\n",
+		},
+		{
+			name:    "add dependencies to the first document",
+			yaml:    "rig: https://github.com/fishworks/fish-food\n# the second document\n---\nrig: https://github.com/example/rig\n",
+			food:    "helm",
+			version: "3.3.4",
+			want:    "rig: https://github.com/fishworks/fish-food\n# the second document\ndependencies:\n- rig: https://github.com/fishworks/fish-food\n  food: helm\n  version: 3.3.4\n---\nrig: https://github.com/example/rig\n",
+		},
+		{
+			name:    "add to dependencies in the first document",
+			yaml:    "---\nrig: https://github.com/fishworks/fish-food\ndependencies:\n- rig: https://github.com/fishworks/fish-food\n  food: helm\n...\n---\ndependencies:\n- food: helm\n",
+			food:    "kubectl",
+			version: "1.18.0",
+			want:    "---\nrig: https://github.com/fishworks/fish-food\ndependencies:\n- rig: https://github.com/fishworks/fish-food\n  food: helm\n- rig: https://github.com/fishworks/fish-food\n  food: kubectl\n  version: 1.18.0\n...\n---\ndependencies:\n- food: helm\n",
+		},
+		{
+			name: "add to empty dependencies",
+			yaml: "rig: &rig https://github.com/fishworks/fish-food\ndependencies: [] # none yet\n",
+			food: "helm",
+			want: "rig: &rig https://github.com/fishworks/fish-food\ndependencies: # none yet\n- rig: *rig\n  food: helm\n",
+		},
+		{
+			name: "remove version",
+			yaml: "rig: &rig https://github.com/fishworks/fish-food\ndependencies:\n-\n  food: helm\n  version: '>= 3.3.0'\n  rig: *rig\n",
+			food: "helm",
+			want: "rig: &rig https://github.com/fishworks/fish-food\ndependencies:\n-\n  food: helm\n  rig: *rig\n",
+		},
+		{
+			name: "remove folded version",
+			yaml: "rig: &rig https://github.com/fishworks/fish-food\ndependencies:\n- rig: *rig\n  version: >-\n    >= 3.3.0\n\n    < 4.0.0\n  food: helm\n",
+			food: "helm",
+			want: "rig: &rig https://github.com/fishworks/fish-food\ndependencies:\n- rig: *rig\n  food: helm\n",
+		},
+		{
+			name:    "update folded version",
+			yaml:    "rig: &rig https://github.com/fishworks/fish-food\ndependencies:\n- rig: *rig\n  food: helm\n  version: >-\n    >= 3.3.0\n# kubectl\n- rig: *rig\n  food: kubectl\n",
+			food:    "helm",
+			version: "3.4.0",
+			want:    "rig: &rig https://github.com/fishworks/fish-food\ndependencies:\n- rig: *rig\n  food: helm\n  version: 3.4.0\n# kubectl\n- rig: *rig\n  food: kubectl\n",
+		},
+		{
+			name:    "update multi-line foods",
+			yaml:    "rig: https://github.com/fishworks/fish-food\nfoods:\n  helm: '>= 3.3.0,\n    < 4.0.0'\n  kubectl: 1.18.0\n",
+			food:    "helm",
+			version: "3.4.0",
+			want:    "rig: https://github.com/fishworks/fish-food\nfoods:\n  helm: 3.4.0\n  kubectl: 1.18.0\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := openTestConfigFile(t, tc.yaml)
+
+			if err := f.SetDependency(tc.rig, tc.food, tc.version); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := string(f.Bytes()); got != tc.want {
+				t.Errorf("want:\n%s\ngot:\n%s", tc.want, got)
+			}
+
+			if _, err := ParseConfig(f.Bytes()); err != nil {
+				t.Errorf("edited config is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigFileRemoveDependency(t *testing.T) {
+	testcases := []struct {
+		name      string
+		yaml      string
+		rig, food string
+		want      string
+	}{
+		{
+			name: "remove with comments",
+			yaml: editedConfig,
+			food: "helm",
+			want: `# The rig all the foods are read from
+rig: &rig https://github.com/fishworks/fish-food
+
+dependencies:
+- rig: https://github.com/example/rig
+  food: kubectl
+  vars:
+    variant: fips
+
+# Tools for the e2e tests
+helm:
+  plugins:
+    diff: 3.1.3
+`,
+		},
+		{
+			name: "remove last",
+			yaml: editedConfig,
+			rig:  "https://github.com/example/rig",
+			food: "kubectl",
+			want: `# The rig all the foods are read from
+rig: &rig https://github.com/fishworks/fish-food
+
+dependencies:
+# helm is used by helmfile
+- rig: *rig
+  food: helm
+  version: ">= 3.3.0" # keep in sync with CI
+
+# Tools for the e2e tests
+helm:
+  plugins:
+    diff: 3.1.3
+`,
+		},
+		{
+			name: "remove from foods and dependencies",
+			yaml: "rig: https://github.com/fishworks/fish-food\nfoods:\n  helm: 3.3.4\ndependencies:\n- rig: https://github.com/example/rig\n  food: helm\n- rig: https://github.com/example/rig\n  food: kubectl\n",
+			food: "helm",
+			want: "rig: https://github.com/fishworks/fish-food\ndependencies:\n- rig: https://github.com/example/rig\n  food: kubectl\n",
+		},
+		{
+			name: "remove from the first document",
+			yaml: "rig: https://github.com/fishworks/fish-food\ndependencies:\n- rig: https://github.com/fishworks/fish-food\n  food: helm\n- rig: https://github.com/fishworks/fish-food\n  food: kubectl\n---\nrig: https://github.com/example/rig\nfoods:\n  kubectl: 1.18.0\n",
+			food: "kubectl",
+			want: "rig: https://github.com/fishworks/fish-food\ndependencies:\n- rig: https://github.com/fishworks/fish-food\n  food: helm\n---\nrig: https://github.com/example/rig\nfoods:\n  kubectl: 1.18.0\n",
+		},
+		{
+			name: "remove multi-line foods",
+			yaml: "rig: https://github.com/fishworks/fish-food\nfoods:\n  helm: >\n    >= 3.3.0\n  kubectl: 1.18.0\n",
+			food: "helm",
+			want: "rig: https://github.com/fishworks/fish-food\nfoods:\n  kubectl: 1.18.0\n",
+		},
+		{
+			name: "remove the only multi-line food",
+			yaml: "rig: https://github.com/fishworks/fish-food\nfoods:\n  helm:\n    3.3.0\nhelm:\n  plugins:\n    diff: 3.1.3\n",
+			food: "helm",
+			want: "rig: https://github.com/fishworks/fish-food\nhelm:\n  plugins:\n    diff: 3.1.3\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := openTestConfigFile(t, tc.yaml)
+
+			removed, err := f.RemoveDependency(tc.rig, tc.food)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !removed {
+				t.Fatal("want removed, got not found")
+			}
+
+			if got := string(f.Bytes()); got != tc.want {
+				t.Errorf("want:\n%s\ngot:\n%s", tc.want, got)
+			}
+
+			if _, err := ParseConfig(f.Bytes()); err != nil {
+				t.Errorf("edited config is invalid: %v", err)
+			}
+		})
+	}
+
+	f := openTestConfigFile(t, editedConfig)
+
+	if removed, err := f.RemoveDependency("https://github.com/example/rig", "helm"); err != nil || removed {
+		t.Errorf("want not found, got removed=%v, err=%v", removed, err)
+	}
+}
+
+func openTestConfigFile(t *testing.T, yaml string) *ConfigFile {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "shoal-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "shoal.yaml")
+
+	if err := ioutil.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
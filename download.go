@@ -0,0 +1,146 @@
+package shoal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/fishworks/gofish"
+)
+
+const downloadProgressInterval = 500 * time.Millisecond
+
+// download fetches the package into filePath, trying the package's mirrors when the primary URL fails.
+// Each URL is rewritten by the mirrors given to WithMirrors, and retried according to the download retry policy.
+// An existing file at filePath is reused as the cache.
+// The returned error wraps the error of the last URL, like ErrTimeout and ErrHTTPStatus.
+func (a *App) download(f *gofish.Food, pkg *gofish.Package, filePath string) error {
+	if _, err := os.Stat(filePath); err == nil {
+		return nil
+	}
+
+	urls := append([]string{pkg.URL}, pkg.Mirrors...)
+
+	var lastErr error
+
+	for _, u := range urls {
+		u = a.rewriteURL(u)
+
+		ev := Event{Food: f.Name, Version: f.Version, URL: u}
+
+		if err := a.retry(a.downloadRetry, "downloading "+u, ev, func() error {
+			return a.downloadFile(f, u, filePath)
+		}); err != nil {
+			a.logger.Printf("downloading %s: %v", u, err)
+			lastErr = err
+			continue
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("failed to download package for OS/arch %s/%s with URL %s to filepath %s: %w", pkg.OS, pkg.Arch, pkg.URL, filePath, lastErr)
+}
+
+// downloadFile downloads the URL into filePath. The download is aborted with ErrTimeout when it exceeds the download timeout.
+func (a *App) downloadFile(f *gofish.Food, url, filePath string) error {
+	ctx, cancel := a.downloadContext()
+	defer cancel()
+
+	err := a.fetchFile(ctx, f, url, filePath)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout{Operation: "download", URL: url, Timeout: a.downloadTimeout}
+	}
+
+	return err
+}
+
+// downloadContext returns the context for a download, which is canceled after the download timeout.
+func (a *App) downloadContext() (context.Context, context.CancelFunc) {
+	if a.downloadTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), a.downloadTimeout)
+}
+
+func (a *App) fetchFile(ctx context.Context, f *gofish.Food, url, filePath string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return ErrHTTPStatus{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	// Download into a temporary file so that an interrupted download isn't mistaken for the cache
+	tmp := filePath + ".download"
+
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer out.Close()
+
+	p := &progressWriter{
+		app: a,
+		event: Event{
+			Type:       EventDownloadProgress,
+			Food:       f.Name,
+			Version:    f.Version,
+			URL:        url,
+			BytesTotal: resp.ContentLength,
+		},
+	}
+
+	if _, err := io.Copy(io.MultiWriter(out, p), resp.Body); err != nil {
+		return err
+	}
+
+	if p.event.BytesDownloaded != p.reportedBytes {
+		p.report()
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filePath)
+}
+
+// progressWriter emits DownloadProgress events for the bytes written to it, at most once per downloadProgressInterval.
+type progressWriter struct {
+	app   *App
+	event Event
+
+	lastReported  time.Time
+	reportedBytes int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.event.BytesDownloaded += int64(len(b))
+
+	if time.Since(p.lastReported) >= downloadProgressInterval {
+		p.report()
+	}
+
+	return len(b), nil
+}
+
+func (p *progressWriter) report() {
+	p.lastReported = time.Now()
+	p.reportedBytes = p.event.BytesDownloaded
+
+	p.app.emit(p.event)
+}
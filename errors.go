@@ -0,0 +1,232 @@
+package shoal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNoMatchingVersion is returned when none of the food versions found in the rig satisfies the constraint.
+type ErrNoMatchingVersion struct {
+	Rig        string
+	Food       string
+	Constraint string
+	// Versions are all the versions of the food found in the rig, newest first.
+	// Empty when the rig has no food with the name.
+	Versions []string
+}
+
+func (e ErrNoMatchingVersion) Error() string {
+	if len(e.Versions) == 0 {
+		return fmt.Sprintf("finding food: no food named %q found in rig %q", e.Food, e.Rig)
+	}
+
+	return fmt.Sprintf(
+		"finding food: no food matching the semver constraint %q found out of %d food versions",
+		e.Constraint,
+		len(e.Versions),
+	)
+}
+
+// ErrBrokenCache is returned when shoal finds something unexpected in its root dir, which needs to be removed by the user.
+type ErrBrokenCache struct {
+	// Path is the path to the file or the directory to be removed.
+	Path string
+	// Reason describes what's wrong with it.
+	Reason string
+}
+
+func (e ErrBrokenCache) Error() string {
+	return fmt.Sprintf("broken shoal cache: %s: please remove %s and try again", e.Reason, e.Path)
+}
+
+// ErrGit is returned when a git operation fails.
+type ErrGit struct {
+	// Command is the git command that failed, like `git fetch origin master`.
+	Command string
+	// Output is the output of the git command. It is empty for the go-git provider.
+	Output string
+	Err    error
+}
+
+func (e ErrGit) Error() string {
+	if e.Output == "" {
+		return fmt.Sprintf("running %s: %v", e.Command, e.Err)
+	}
+
+	return fmt.Sprintf("running %s: %v\n\nOUTPUT:\n%s", e.Command, e.Err, e.Output)
+}
+
+func (e ErrGit) Unwrap() error {
+	return e.Err
+}
+
+// ErrHTTPStatus is returned when the server responded to a download with a non-2xx status.
+type ErrHTTPStatus struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e ErrHTTPStatus) Error() string {
+	return fmt.Sprintf("unexpected status: %s", e.Status)
+}
+
+// ErrTimeout is returned when a git operation or a download didn't complete within the configured timeout.
+type ErrTimeout struct {
+	// Operation is what timed out, like `git clone` or `download`.
+	Operation string
+	// Rig is the rig being cloned or read. Empty for downloads.
+	Rig string
+	// URL is the URL being downloaded. Empty for git operations.
+	URL     string
+	Timeout time.Duration
+}
+
+func (e ErrTimeout) Error() string {
+	if e.URL != "" {
+		return fmt.Sprintf("%s %s timed out after %s", e.Operation, e.URL, e.Timeout)
+	}
+
+	if e.Rig != "" {
+		return fmt.Sprintf("%s for rig %q timed out after %s", e.Operation, e.Rig, e.Timeout)
+	}
+
+	return fmt.Sprintf("%s timed out after %s", e.Operation, e.Timeout)
+}
+
+// withRig sets the rig of the ErrTimeout, for git operations that don't know which rig they are run for.
+func withRig(err error, rig string) error {
+	if t, ok := err.(ErrTimeout); ok && t.Rig == "" {
+		t.Rig = rig
+		return t
+	}
+
+	return err
+}
+
+// ErrRottenFood is a revision of the food definition in the rig that failed to evaluate,
+// because of a syntax error, a runtime error, or a timeout.
+type ErrRottenFood struct {
+	Rig          string
+	Food         string
+	FoodCommitID string
+	Err          error
+}
+
+func (e ErrRottenFood) Error() string {
+	return fmt.Sprintf("rotten food %q at commit %s in rig %q: %v", e.Food, shortCommitID(e.FoodCommitID), e.Rig, e.Err)
+}
+
+func (e ErrRottenFood) Unwrap() error {
+	return e.Err
+}
+
+// ErrRottenFoods is returned in the strict foods mode when the selected revision of the food definition
+// or any newer one is rotten. It is also returned in any mode when every revision of the food definition is rotten.
+type ErrRottenFoods []ErrRottenFood
+
+func (e ErrRottenFoods) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d rotten revisions of the food found:", len(e))
+
+	for _, r := range e {
+		fmt.Fprintf(&b, "\n- %v", r)
+	}
+
+	return b.String()
+}
+
+// Is allows errors.Is to find errors of any rotten revision.
+// It is implemented explicitly, as errors.Is doesn't unwrap a list of errors before Go 1.20.
+func (e ErrRottenFoods) Is(target error) bool {
+	for _, r := range e {
+		if errors.Is(r, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As allows errors.As to find errors of any rotten revision, the newest one first.
+func (e ErrRottenFoods) As(target interface{}) bool {
+	for _, r := range e {
+		if errors.As(r, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ErrInstall is returned when shoal failed to download, unpack or link the selected version of the food.
+type ErrInstall struct {
+	Food    string
+	Version string
+	Err     error
+}
+
+func (e ErrInstall) Error() string {
+	return fmt.Sprintf("installing %s %s: %v", e.Food, e.Version, e.Err)
+}
+
+func (e ErrInstall) Unwrap() error {
+	return e.Err
+}
+
+// ErrDependency is the failure of a single dependency in a config.
+type ErrDependency struct {
+	Rig        string
+	Food       string
+	Constraint string
+	Err        error
+}
+
+func (e ErrDependency) Error() string {
+	return fmt.Sprintf("food %q from rig %q with constraint %q: %v", e.Food, e.Rig, e.Constraint, e.Err)
+}
+
+func (e ErrDependency) Unwrap() error {
+	return e.Err
+}
+
+// ErrDependencies is returned by Sync and Plan in the keep-going mode, listing all the failed dependencies.
+type ErrDependencies []ErrDependency
+
+func (e ErrDependencies) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d dependencies failed:", len(e))
+
+	for _, d := range e {
+		fmt.Fprintf(&b, "\n- %v", d)
+	}
+
+	return b.String()
+}
+
+// Is allows errors.Is to find errors of any failed dependency.
+// It is implemented explicitly, as errors.Is doesn't unwrap a list of errors before Go 1.20.
+func (e ErrDependencies) Is(target error) bool {
+	for _, d := range e {
+		if errors.Is(d, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As allows errors.As to find errors of any failed dependency, the first one first.
+func (e ErrDependencies) As(target interface{}) bool {
+	for _, d := range e {
+		if errors.As(d, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+package shoal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSyncErrors(t *testing.T) {
+	packages := newTestPackages(t)
+
+	rig := testRig(t, packages.food(t, "foo", "1.0.0", "linux/amd64"), packages.food(t, "foo", "1.1.0", "linux/amd64"))
+	defer os.RemoveAll(rig)
+
+	// The package of foo 2.0.0 isn't served
+	missing := testRig(t, strings.Replace(packages.food(t, "foo", "2.0.0", "linux/amd64"), packages.URL, packages.URL+"/missing", 1))
+	defer os.RemoveAll(missing)
+
+	sync := func(t *testing.T, root string, d Dependency) error {
+		config := Config{Dependencies: []Dependency{d}}
+
+		return testApp(t, root, config, Target("linux", "amd64"), WithGitRetry(RetryPolicy{Attempts: 1}), WithDownloadRetry(RetryPolicy{Attempts: 1})).Sync(config)
+	}
+
+	t.Run("no matching version", func(t *testing.T) {
+		err := sync(t, testRoot(t), Dependency{Rig: rig, Food: "foo", Version: ">= 2.0.0"})
+
+		var e ErrNoMatchingVersion
+
+		if !errors.As(err, &e) {
+			t.Fatalf("want ErrNoMatchingVersion, got %v", err)
+		}
+
+		if e.Constraint != ">= 2.0.0" || strings.Join(e.Versions, ",") != "1.1.0,1.0.0" {
+			t.Errorf("want the constraint and the versions in the rig, got %+v", e)
+		}
+	})
+
+	t.Run("git", func(t *testing.T) {
+		notFound := filepath.Join(testRoot(t), "not-found")
+
+		err := sync(t, testRoot(t), Dependency{Rig: notFound, Food: "foo", Version: "1.0.0"})
+
+		var e ErrGit
+
+		if !errors.As(err, &e) {
+			t.Fatalf("want ErrGit, got %v", err)
+		}
+
+		if !strings.HasPrefix(e.Command, "git clone") || e.Output == "" {
+			t.Errorf("want the git command and its output, got %+v", e)
+		}
+	})
+
+	t.Run("broken cache", func(t *testing.T) {
+		root := testRoot(t)
+
+		// A workspace without the RIG file identifying the rig
+		workspace := filepath.Join(root, "workspaces", rigKey(rig), "broken")
+
+		if err := os.MkdirAll(workspace, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		err := sync(t, root, Dependency{Rig: rig, Food: "foo", Version: "1.0.0"})
+
+		var e ErrBrokenCache
+
+		if !errors.As(err, &e) {
+			t.Fatalf("want ErrBrokenCache, got %v", err)
+		}
+
+		if e.Path != workspace {
+			t.Errorf("want the path to the workspace, got %q", e.Path)
+		}
+	})
+
+	t.Run("install", func(t *testing.T) {
+		err := sync(t, testRoot(t), Dependency{Rig: missing, Food: "foo", Version: "2.0.0"})
+
+		var e ErrInstall
+
+		if !errors.As(err, &e) {
+			t.Fatalf("want ErrInstall, got %v", err)
+		}
+
+		if e.Food != "foo" || e.Version != "2.0.0" {
+			t.Errorf("want the food and the version, got %+v", e)
+		}
+	})
+}
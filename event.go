@@ -0,0 +1,91 @@
+package shoal
+
+import (
+	"time"
+)
+
+// EventType identifies what happened in an Event.
+type EventType string
+
+const (
+	// EventResolveStarted is emitted before shoal starts looking for the food version that satisfies the constraint.
+	EventResolveStarted EventType = "ResolveStarted"
+	// EventVersionsListed is emitted after shoal has read all the versions of the food from the rig.
+	EventVersionsListed EventType = "VersionsListed"
+	// EventVersionSelected is emitted when shoal has selected the version to install.
+	EventVersionSelected EventType = "VersionSelected"
+	// EventDownloadProgress is emitted periodically while a package is being downloaded.
+	EventDownloadProgress EventType = "DownloadProgress"
+	// EventInstalled is emitted after a food is installed.
+	EventInstalled EventType = "Installed"
+	// EventSkipped is emitted when shoal skips installing a food.
+	EventSkipped EventType = "Skipped"
+	// EventFailed is emitted when shoal failed to resolve or install a food.
+	EventFailed EventType = "Failed"
+	// EventRetrying is emitted when a git operation or a download failed and is going to be retried.
+	EventRetrying EventType = "Retrying"
+	// EventRottenFood is emitted for each revision of the food definition that failed to evaluate and was skipped.
+	EventRottenFood EventType = "RottenFood"
+	// EventDirectoryCreated is emitted by Init for each directory it creates.
+	EventDirectoryCreated EventType = "DirectoryCreated"
+)
+
+// Event describes the progress of resolving and installing a food.
+// Fields irrelevant to the Type are left empty.
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	Rig        string
+	Food       string
+	Constraint string
+
+	// Version and FoodCommitID are set once the version has been selected.
+	// For RottenFood, FoodCommitID is the commit of the rotten revision.
+	Version      string
+	FoodCommitID string
+
+	// Versions lists all the versions found in the rig, newest first. Set for VersionsListed.
+	Versions []string
+
+	// URL, BytesDownloaded and BytesTotal are set for DownloadProgress.
+	// BytesTotal is -1 when the server didn't tell the size.
+	URL             string
+	BytesDownloaded int64
+	BytesTotal      int64
+
+	// Caveats are the notes of the food for the user, like how to configure it. Set for Installed.
+	Caveats string
+
+	// Dir is the created directory. Set for DirectoryCreated.
+	Dir string
+
+	// Reason explains why the food was skipped. Set for Skipped.
+	// For Retrying, it is the operation to be retried, like `git clone https://github.com/fishworks/fish-food`.
+	Reason string
+
+	// Attempt is the number of the failed attempt, and Delay is the time to wait before the next one. Set for Retrying.
+	Attempt int
+	Delay   time.Duration
+
+	// Err is set for Failed, Retrying and RottenFood.
+	Err error
+}
+
+// WithEventHandler registers the function to be called on every Event.
+// The handler is called synchronously, so it should return quickly.
+func WithEventHandler(h func(Event)) Option {
+	return func(app *App) {
+		app.eventHandler = h
+	}
+}
+
+func (a *App) emit(e Event) {
+	if a.eventHandler == nil {
+		return
+	}
+
+	e.Time = time.Now()
+
+	a.eventHandler(e)
+}
@@ -0,0 +1,363 @@
+package shoal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// Installs are staged into generations.
+//
+// A generation is a directory under `.shoal/generations` containing the links to the installed foods
+// and the record of them. `.shoal/bin` is a symlink to the bin dir of the current generation,
+// which is swapped atomically once all the dependencies in a sync are installed.
+// The generation that was current before the last sync is kept so that it can be restored by Rollback.
+
+const generationsDirName = "generations"
+
+type generation struct {
+	id  int
+	dir string
+
+	// changed is set when any food is installed into the generation
+	changed bool
+}
+
+func (g *generation) binDir() string {
+	return filepath.Join(g.dir, "bin")
+}
+
+func (g *generation) installedFoodsPath() string {
+	return filepath.Join(g.dir, installedFoodsFilename)
+}
+
+func (a *App) generationsDir() string {
+	return filepath.Join(a.RootDir, generationsDirName)
+}
+
+func (a *App) generation(id int) *generation {
+	return &generation{
+		id:  id,
+		dir: filepath.Join(a.generationsDir(), strconv.Itoa(id)),
+	}
+}
+
+// generations returns the IDs of all the existing generations in ascending order.
+func (a *App) generations() ([]int, error) {
+	infos, err := ioutil.ReadDir(a.generationsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ids []int
+
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+
+		id, err := strconv.Atoi(info.Name())
+		if err != nil {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
+// currentGeneration returns the generation `.shoal/bin` points to.
+// A missing bin dir, or a bin dir created by an older version of shoal, is turned into the first generation.
+func (a *App) currentGeneration() (*generation, error) {
+	gen, err := a.findCurrentGeneration()
+	if err != nil || gen != nil {
+		return gen, err
+	}
+
+	info, err := os.Lstat(a.BinPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return a.migrateToGenerations(info != nil)
+}
+
+// findCurrentGeneration returns the generation `.shoal/bin` points to, without changing anything.
+// It returns nil when the bin dir is missing or created by an older version of shoal.
+func (a *App) findCurrentGeneration() (*generation, error) {
+	binPath := a.BinPath()
+
+	info, err := os.Lstat(binPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if info == nil || info.Mode()&os.ModeSymlink == 0 {
+		return nil, nil
+	}
+
+	target, err := os.Readlink(binPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The link is relative to the root dir, like `generations/1/bin`
+	id, err := strconv.Atoi(filepath.Base(filepath.Dir(target)))
+	if err != nil {
+		return nil, ErrBrokenCache{Path: binPath, Reason: fmt.Sprintf("%s points to %s which isn't a generation", binPath, target)}
+	}
+
+	return a.generation(id), nil
+}
+
+func (a *App) migrateToGenerations(binDirExists bool) (*generation, error) {
+	gen := a.generation(0)
+
+	if err := os.MkdirAll(gen.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	if binDirExists {
+		a.logger.Printf("moving %s into generation %d", a.BinPath(), gen.id)
+
+		if err := os.Rename(a.BinPath(), gen.binDir()); err != nil {
+			return nil, fmt.Errorf("migrating bin dir: %w", err)
+		}
+	} else if err := os.MkdirAll(gen.binDir(), 0755); err != nil {
+		return nil, err
+	}
+
+	legacyInstalledFoodsPath := filepath.Join(a.RootDir, installedFoodsFilename)
+
+	if _, err := os.Stat(legacyInstalledFoodsPath); err == nil {
+		if err := os.Rename(legacyInstalledFoodsPath, gen.installedFoodsPath()); err != nil {
+			return nil, fmt.Errorf("migrating installed foods: %w", err)
+		}
+	}
+
+	if err := a.switchGeneration(gen); err != nil {
+		return nil, err
+	}
+
+	return gen, nil
+}
+
+// switchGeneration atomically points `.shoal/bin` to the bin dir of the generation.
+func (a *App) switchGeneration(gen *generation) error {
+	binPath := a.BinPath()
+
+	target, err := filepath.Rel(a.RootDir, gen.binDir())
+	if err != nil {
+		return err
+	}
+
+	tmp := fmt.Sprintf("%s.%d.tmp", binPath, gen.id)
+
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+
+	if err := os.Symlink(target, tmp); err != nil {
+		return fmt.Errorf("linking generation %d: %w", gen.id, err)
+	}
+
+	if err := os.Rename(tmp, binPath); err != nil {
+		return fmt.Errorf("switching to generation %d: %w", gen.id, err)
+	}
+
+	return nil
+}
+
+// beginGeneration creates a new generation populated with the links and records of the current generation.
+// The new generation is empty when the current one is missing, like when its directory has been removed by hand.
+func (a *App) beginGeneration() (*generation, *generation, error) {
+	cur, err := a.currentGeneration()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids, err := a.generations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next := a.generation(cur.id + 1)
+
+	for _, id := range ids {
+		if id >= next.id {
+			next = a.generation(id + 1)
+		}
+	}
+
+	a.logger.Printf("staging installs into generation %d", next.id)
+
+	if _, err = os.Stat(cur.dir); os.IsNotExist(err) {
+		// Start over from an empty generation when the current one has been removed
+		a.logger.Printf("generation %d is missing. starting from an empty generation", cur.id)
+
+		err = os.MkdirAll(next.binDir(), 0755)
+	} else if err == nil {
+		err = copyTree(cur.dir, next.dir)
+	}
+
+	if err != nil {
+		os.RemoveAll(next.dir)
+
+		return nil, nil, fmt.Errorf("creating generation %d: %w", next.id, err)
+	}
+
+	return cur, next, nil
+}
+
+// commitGeneration makes the staged generation current, and removes the generations other than it and
+// the previous one.
+func (a *App) commitGeneration(prev, gen *generation) error {
+	if err := a.switchGeneration(gen); err != nil {
+		return err
+	}
+
+	a.logger.Printf("switched to generation %d", gen.id)
+
+	ids, err := a.generations()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if id == gen.id || id == prev.id {
+			continue
+		}
+
+		if err := os.RemoveAll(a.generation(id).dir); err != nil {
+			return fmt.Errorf("removing generation %d: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// withGeneration runs f against a new generation and makes it current only when f succeeds and
+// installs anything.
+func (a *App) withGeneration(f func(*generation) error) error {
+	a.generationMutex.Lock()
+	defer a.generationMutex.Unlock()
+
+	prev, gen, err := a.beginGeneration()
+	if err != nil {
+		return err
+	}
+
+	var committed bool
+
+	defer func() {
+		if committed {
+			return
+		}
+
+		a.logger.Printf("discarding generation %d", gen.id)
+
+		if err := os.RemoveAll(gen.dir); err != nil {
+			a.logger.Printf("removing generation %d: %v", gen.id, err)
+		}
+	}()
+
+	if err := f(gen); err != nil {
+		return err
+	}
+
+	// Keep the previous generation restorable by Rollback when nothing has changed
+	if !gen.changed {
+		return nil
+	}
+
+	if err := a.commitGeneration(prev, gen); err != nil {
+		return err
+	}
+
+	committed = true
+
+	return nil
+}
+
+// Rollback makes the generation that was current before the last sync current again.
+// It returns the ID of the restored generation.
+func (a *App) Rollback() (int, error) {
+	a.setEnv()
+
+	a.generationMutex.Lock()
+	defer a.generationMutex.Unlock()
+
+	cur, err := a.currentGeneration()
+	if err != nil {
+		return 0, err
+	}
+
+	ids, err := a.generations()
+	if err != nil {
+		return 0, err
+	}
+
+	prev := -1
+
+	for _, id := range ids {
+		if id < cur.id {
+			prev = id
+		}
+	}
+
+	if prev < 0 {
+		return 0, fmt.Errorf("no generation to roll back to: generation %d is the oldest one", cur.id)
+	}
+
+	if err := a.switchGeneration(a.generation(prev)); err != nil {
+		return 0, err
+	}
+
+	a.logger.Printf("rolled back from generation %d to %d", cur.id, prev)
+
+	return prev, nil
+}
+
+// copyTree copies the directory tree, recreating symlinks rather than copying what they point to.
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		dstPath := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(dstPath, 0755)
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(target, dstPath)
+		default:
+			bs, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			return ioutil.WriteFile(dstPath, bs, info.Mode())
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package shoal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestMigrateToGenerations(t *testing.T) {
+	root := testRoot(t)
+
+	// The bin dir and the record of the installed foods created by an older version of shoal
+	if err := os.MkdirAll(filepath.Join(root, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(root, "bin", "foo"), []byte("foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(root, installedFoodsFilename), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := testApp(t, root, Config{})
+
+	if err := app.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Lstat(filepath.Join(root, "bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("want the bin dir to be a symlink, got %s", info.Mode())
+	}
+
+	gen := app.generation(0)
+
+	if got := readLink(t, filepath.Join(root, "bin"), "foo"); got != "foo" {
+		t.Errorf("want the migrated file in the bin dir, got %q", got)
+	}
+
+	if _, err := os.Stat(filepath.Join(gen.binDir(), "foo")); err != nil {
+		t.Errorf("want the file to be moved into generation 0: %v", err)
+	}
+
+	if _, err := os.Stat(gen.installedFoodsPath()); err != nil {
+		t.Errorf("want the installed foods to be moved into generation 0: %v", err)
+	}
+}
+
+func TestPlanWithoutGenerations(t *testing.T) {
+	packages := newTestPackages(t)
+
+	rig := testRig(t, packages.food(t, "foo", "1.0.0", "linux/amd64"), packages.food(t, "foo", "2.0.0", "linux/amd64"))
+	defer os.RemoveAll(rig)
+
+	root := testRoot(t)
+
+	// The bin dir and the record of the installed foods created by an older version of shoal
+	if err := os.MkdirAll(filepath.Join(root, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	installed := `{"foo": {"name": "foo", "version": "1.0.0", "installPaths": ["bin/foo"]}}`
+
+	if err := ioutil.WriteFile(filepath.Join(root, installedFoodsFilename), []byte(installed), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{Dependencies: []Dependency{{Rig: rig, Food: "foo", Version: "2.0.0"}}}
+
+	plan, err := testApp(t, root, config, Target("linux", "amd64")).Plan(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := plan.Dependencies[0]; got.InstalledVersion != "1.0.0" || got.Action != PlanActionUpgrade {
+		t.Errorf("want an upgrade from 1.0.0, got %+v", got)
+	}
+
+	// The plan leaves the bin dir to be migrated by the next sync
+	info, err := os.Lstat(filepath.Join(root, "bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !info.IsDir() {
+		t.Errorf("want the bin dir to be left as is, got %s", info.Mode())
+	}
+
+	if _, err := os.Stat(filepath.Join(root, generationsDirName)); !os.IsNotExist(err) {
+		t.Errorf("want no generation to be created, got %v", err)
+	}
+}
+
+func TestSyncGenerations(t *testing.T) {
+	host := runtime.GOOS + "/" + runtime.GOARCH
+
+	packages := newTestPackages(t)
+
+	rig := testRig(t, packages.food(t, "foo", "1.0.0", host), packages.food(t, "foo", "2.0.0", host))
+	defer os.RemoveAll(rig)
+
+	root := testRoot(t)
+	bin := filepath.Join(root, "bin")
+
+	sync := func(version string) error {
+		config := Config{Dependencies: []Dependency{{Rig: rig, Food: "foo", Version: version}}}
+
+		return testApp(t, root, config).Sync(config)
+	}
+
+	wantGenerations := func(current int, ids ...int) {
+		t.Helper()
+
+		app := testApp(t, root, Config{})
+
+		cur, err := app.currentGeneration()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cur.id != current {
+			t.Errorf("want generation %d to be current, got %d", current, cur.id)
+		}
+
+		got, err := app.generations()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != len(ids) {
+			t.Fatalf("want generations %v, got %v", ids, got)
+		}
+
+		for i := range ids {
+			if got[i] != ids[i] {
+				t.Fatalf("want generations %v, got %v", ids, got)
+			}
+		}
+	}
+
+	if err := sync("1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	wantGenerations(1, 0, 1)
+
+	if err := sync("2.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The previous generation is kept for Rollback, and the older ones are removed
+	wantGenerations(2, 1, 2)
+
+	if got, want := readLink(t, bin, "foo"), packageContent("foo", "2.0.0", host); got != want {
+		t.Errorf("want foo 2.0.0, got %q", got)
+	}
+
+	// The generation staged by a failed sync is discarded, leaving the current one untouched
+	if err := sync("3.0.0"); err == nil {
+		t.Fatal("want error for the missing version")
+	}
+
+	wantGenerations(2, 1, 2)
+
+	if got, want := readLink(t, bin, "foo"), packageContent("foo", "2.0.0", host); got != want {
+		t.Errorf("want foo 2.0.0 after the failed sync, got %q", got)
+	}
+
+	app := testApp(t, root, Config{})
+
+	id, err := app.Rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != 1 {
+		t.Errorf("want generation 1 to be restored, got %d", id)
+	}
+
+	if got, want := readLink(t, bin, "foo"), packageContent("foo", "1.0.0", host); got != want {
+		t.Errorf("want foo 1.0.0 after the rollback, got %q", got)
+	}
+
+	if _, err := app.Rollback(); err == nil {
+		t.Error("want error for rolling back the oldest generation")
+	}
+}
+
+func TestSyncMissingGeneration(t *testing.T) {
+	host := runtime.GOOS + "/" + runtime.GOARCH
+
+	packages := newTestPackages(t)
+
+	rig := testRig(t, packages.food(t, "foo", "1.0.0", host))
+	defer os.RemoveAll(rig)
+
+	root := testRoot(t)
+
+	// The bin dir points to a generation that has been removed
+	if err := os.Symlink(filepath.Join(generationsDirName, "5", "bin"), filepath.Join(root, "bin")); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{Dependencies: []Dependency{{Rig: rig, Food: "foo", Version: "1.0.0"}}}
+
+	if err := testApp(t, root, config).Sync(config); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := readLink(t, filepath.Join(root, "bin"), "foo"), packageContent("foo", "1.0.0", host); got != want {
+		t.Errorf("want foo 1.0.0, got %q", got)
+	}
+}
@@ -3,6 +3,7 @@ package shoal
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/go-git/go-git/v5/config"
@@ -10,6 +11,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"os/exec"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-git/go-git/v5"
@@ -19,6 +21,8 @@ type GitClient interface {
 	Fetch(dir string, ref string) error
 	ForceCheckout(dir string, ref string) error
 	Clone(repo string, dir string) error
+	// Log returns the commits that changed the file, newest first.
+	// Each line is formatted as `<commit ID> <subject>`.
 	Log(string, string) (string, error)
 	Show(string, string, string) (string, error)
 	InitBare(dir string) error
@@ -31,27 +35,142 @@ type GitClient interface {
 	ShowOriginHeadBranch(string) (string, error)
 }
 
+// GitRefResolver is implemented by the GitClients that can resolve refs to commit IDs.
+// It is optional so that existing GitClient implementations keep working, but it is required for including config files
+// from git repositories and for searching rigs. NativeGit and GoGit implement it.
+type GitRefResolver interface {
+	// ResolveRef returns the ID of the commit the branch, tag, or commit ID points to.
+	// Remote branches are resolved too, so that a fresh clone can be read at any branch.
+	ResolveRef(dir string, ref string) (string, error)
+}
+
+// GitDatedLogger is implemented by the GitClients that can log commits along with their dates.
+// It is optional so that existing GitClient implementations keep working. Without it, food versions are listed without dates.
+// NativeGit and GoGit implement it.
+type GitDatedLogger interface {
+	// LogWithDates returns the commits that changed the file, newest first.
+	// Each line is formatted as `<commit ID> <commit time in unix seconds> <subject>`.
+	LogWithDates(dir string, file string) (string, error)
+}
+
+// logWithDates logs the commits that changed the file in the format of GitDatedLogger.
+// The time is 0 when the GitClient doesn't implement GitDatedLogger.
+func logWithDates(g GitClient, dir, file string) (string, error) {
+	if l, ok := g.(GitDatedLogger); ok {
+		return l.LogWithDates(dir, file)
+	}
+
+	out, err := g.Log(dir, file)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	for _, l := range strings.Split(out, "\n") {
+		if items := strings.SplitN(l, " ", 2); len(items) == 2 {
+			fmt.Fprintf(&b, "%s 0 %s\n", items[0], items[1])
+		}
+	}
+
+	return b.String(), nil
+}
+
+// resolveRef resolves the ref with the GitClient, failing when it doesn't implement GitRefResolver.
+func resolveRef(g GitClient, dir, ref string) (string, error) {
+	r, ok := g.(GitRefResolver)
+	if !ok {
+		return "", fmt.Errorf("resolving ref %q: git client %T doesn't implement shoal.GitRefResolver", ref, g)
+	}
+
+	return r.ResolveRef(dir, ref)
+}
+
 type NativeGit struct {
+	// Timeout is the maximum duration of each git command. The command is killed when it exceeds the timeout.
+	// Zero means no timeout.
+	Timeout time.Duration
 }
 
-func (n *NativeGit) ForceCheckout(local, s string) error {
-	gitForceCheckout := exec.Command("git", "checkout", "-B", s)
-	gitForceCheckout.Dir = local
+// run runs the git command in dir and returns its combined output.
+func (n *NativeGit) run(dir string, args ...string) ([]byte, error) {
+	var out bytes.Buffer
 
-	if out, err := gitForceCheckout.CombinedOutput(); err != nil {
-		return fmt.Errorf("%v\n%s", err, string(out))
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := n.exec(cmd, args); err != nil {
+		if _, ok := err.(ErrTimeout); ok {
+			return out.Bytes(), err
+		}
+
+		return out.Bytes(), ErrGit{Command: "git " + strings.Join(args, " "), Output: out.String(), Err: err}
 	}
 
-	return nil
+	return out.Bytes(), nil
+}
+
+// output runs the git command in dir and returns its stdout.
+func (n *NativeGit) output(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := n.exec(cmd, args); err != nil {
+		if _, ok := err.(ErrTimeout); ok {
+			return "", err
+		}
+
+		return "", ErrGit{Command: "git " + strings.Join(args, " "), Output: stderr.String(), Err: err}
+	}
+
+	return stdout.String(), nil
+}
+
+// exec runs the command, killing it along with its children, like git-remote-https, when it exceeds the timeout.
+func (n *NativeGit) exec(cmd *exec.Cmd, args []string) error {
+	if n.Timeout <= 0 {
+		return cmd.Run()
+	}
+
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	var timedOut int32
+
+	t := time.AfterFunc(n.Timeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		killProcessGroup(cmd)
+	})
+
+	err := cmd.Wait()
+
+	t.Stop()
+
+	if atomic.LoadInt32(&timedOut) == 1 {
+		return ErrTimeout{Operation: "git " + args[0], Timeout: n.Timeout}
+	}
+
+	return err
+}
+
+func (n *NativeGit) ForceCheckout(local, s string) error {
+	_, err := n.run(local, "checkout", "-f", "-B", s)
+	return err
 }
 
 func (n *NativeGit) ShowOriginHeadBranch(local string) (string, error) {
 	remote := "origin"
 
-	gitRemoteShowOrigin := exec.Command("git", "remote", "show", remote)
-	gitRemoteShowOrigin.Dir = local
-
-	out, err := gitRemoteShowOrigin.CombinedOutput()
+	out, err := n.run(local, "remote", "show", remote)
 	if err != nil {
 		return "", err
 	}
@@ -79,118 +198,106 @@ func (n *NativeGit) ShowOriginHeadBranch(local string) (string, error) {
 }
 
 func (n *NativeGit) Init(dir string) error {
-	gitInit := exec.Command("git", "init", dir)
-	if err := gitInit.Run(); err != nil {
-		return err
-	}
-	return nil
+	_, err := n.run("", "init", dir)
+	return err
 }
 
 func (n *NativeGit) AddRemote(dir string, name string, url string) error {
-	gitRemoteAdd := exec.Command("git", "remote", "add", name, url)
-	gitRemoteAdd.Dir = dir
-	if out, err := gitRemoteAdd.CombinedOutput(); err != nil {
-		return fmt.Errorf("running git-remote add: %w\n\nCOMBINED OUTPUT:\n%s", err, out)
-	}
-	return nil
+	_, err := n.run(dir, "remote", "add", name, url)
+	return err
 }
 
 func (n *NativeGit) Push(local string, remote string, branch string) error {
-	gitPush := exec.Command("git", "push", remote, branch)
-	gitPush.Dir = local
-	if err := gitPush.Run(); err != nil {
-		return fmt.Errorf("running git-push: %w", err)
-	}
-	return nil
+	_, err := n.run(local, "push", remote, branch)
+	return err
 }
 
 func (n *NativeGit) Commit(dir string, msg string) error {
-	gitCommit := exec.Command("git", "commit", "-m", msg)
-	gitCommit.Dir = dir
-	if out, err := gitCommit.CombinedOutput(); err != nil {
-		return fmt.Errorf("running git-commit: %w\n\nCOMBINED OUTPUT:\n%s", err, string(out))
-	}
-
-	return nil
+	_, err := n.run(dir, "commit", "-m", msg)
+	return err
 }
 
 func (n *NativeGit) Config(tempLocal string, k string, v string) error {
-	gitConfig := exec.Command("git", "config", k, v)
-	gitConfig.Dir = tempLocal
-	if out, err := gitConfig.CombinedOutput(); err != nil {
-		return fmt.Errorf("running git-config: %w\n\nCOMBINED OUTPUT:\n%s", err, string(out))
-	}
-	return nil
+	_, err := n.run(tempLocal, "config", k, v)
+	return err
 }
 
 func (n *NativeGit) Add(tempLocal string, rel string) error {
-	cmd := exec.Command("git", "add", rel)
-	cmd.Dir = tempLocal
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("running git-add: %w", err)
-	}
-	return nil
+	_, err := n.run(tempLocal, "add", rel)
+	return err
 }
 
 func (n *NativeGit) InitBare(tempRemote string) error {
-	gitInit := exec.Command("git", "init", "--bare", tempRemote)
-	if err := gitInit.Run(); err != nil {
-		return err
-	}
-	return nil
+	_, err := n.run("", "init", "--bare", tempRemote)
+	return err
 }
 
-var _ GitClient = &NativeGit{}
+var (
+	_ GitClient      = &NativeGit{}
+	_ GitRefResolver = &NativeGit{}
+	_ GitDatedLogger = &NativeGit{}
+)
 
+// Fetch updates the local branch to the remote one, like GoGit does, so that ForceCheckout checks out the remote changes.
 func (n *NativeGit) Fetch(workspaceDir, ref string) error {
-	gitFetch := exec.Command("git", "fetch", "origin", ref)
-	gitFetch.Dir = workspaceDir
-	if trace, err := gitFetch.CombinedOutput(); err != nil {
-		return fmt.Errorf("running git-fetch: %w\n\nCOMBINED OUTPUT:\n%s", err, trace)
-	}
-	return nil
+	_, err := n.run(workspaceDir, "fetch", "--update-head-ok", "origin", fmt.Sprintf("+refs/heads/%s:refs/heads/%s", ref, ref))
+	return err
 }
 
 func (n *NativeGit) Clone(rig, workspaceDir string) error {
-	gitClone := exec.Command("git", "clone", rig, workspaceDir)
-	if trace, err := gitClone.CombinedOutput(); err != nil {
-		return fmt.Errorf("running git-clone: %w\n\nCOMBINED OUTPUT:\n%s", err, trace)
-	}
-
-	return nil
+	_, err := n.run("", "clone", rig, workspaceDir)
+	return withRig(err, rig)
 }
 
 func (n *NativeGit) Log(workspaceDir, filePath string) (string, error) {
-	var gitLogStdout, gitLogStderr bytes.Buffer
-
-	gitLog := exec.Command("git", "log", "--oneline", "--no-color", "--", filePath)
-	gitLog.Dir = workspaceDir
-	gitLog.Stdout = &gitLogStdout
-	gitLog.Stderr = &gitLogStderr
-	if err := gitLog.Run(); err != nil {
-		return "", fmt.Errorf("running git-log: %w\n\nSTDERR:\n%s", err, gitLogStderr.String())
-	}
-	gitLogOutput := gitLogStdout.String()
+	return n.output(workspaceDir, "log", "--oneline", "--no-color", "--", filePath)
+}
 
-	return gitLogOutput, nil
+func (n *NativeGit) LogWithDates(workspaceDir, filePath string) (string, error) {
+	return n.output(workspaceDir, "log", "--format=%h %ct %s", "--no-color", "--", filePath)
 }
 
 func (n *NativeGit) Show(workspaceDir, commitID, filePath string) (string, error) {
-	var gitShowStdout bytes.Buffer
+	return n.output(workspaceDir, "show", fmt.Sprintf("%s:%s", commitID, filePath))
+}
 
-	gitShow := exec.Command("git", "show", fmt.Sprintf("%s:%s", commitID, filePath))
-	gitShow.Dir = workspaceDir
-	gitShow.Stdout = &gitShowStdout
-	if err := gitShow.Run(); err != nil {
-		return "", fmt.Errorf("running git-show: %w", err)
-	}
+func (n *NativeGit) ResolveRef(dir string, ref string) (string, error) {
+	var err error
 
-	luaScript := gitShowStdout.String()
+	for _, rev := range []string{ref, "origin/" + ref} {
+		var out string
 
-	return luaScript, nil
+		out, err = n.output(dir, "rev-parse", "--verify", rev+"^{commit}")
+		if err == nil {
+			return strings.TrimSpace(out), nil
+		}
+	}
+
+	return "", err
 }
 
 type GoGit struct {
+	// Timeout is the maximum duration of each operation that accesses a remote, like clone, fetch and push.
+	// Zero means no timeout.
+	Timeout time.Duration
+}
+
+// context returns the context for an operation that accesses a remote, which is canceled after the timeout.
+func (n *GoGit) context() (context.Context, context.CancelFunc) {
+	if n.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), n.Timeout)
+}
+
+// timeoutError returns ErrTimeout when the context has timed out, and nil otherwise.
+func (n *GoGit) timeoutError(ctx context.Context, op string) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout{Operation: op, Timeout: n.Timeout}
+	}
+
+	return nil
 }
 
 func (n *GoGit) ForceCheckout(local, s string) error {
@@ -208,7 +315,7 @@ func (n *GoGit) ForceCheckout(local, s string) error {
 		Branch: plumbing.NewBranchReferenceName(s),
 		Force:  true,
 	}); err != nil {
-		return err
+		return ErrGit{Command: "go-git checkout -B " + s, Err: err}
 	}
 
 	return nil
@@ -312,19 +419,30 @@ func (n *GoGit) Push(local string, remote string, branch string) error {
 		return fmt.Errorf("go-git opening %q: %w", local, err)
 	}
 
-	if err := r.Push(&git.PushOptions{
+	ctx, cancel := n.context()
+	defer cancel()
+
+	if err := r.PushContext(ctx, &git.PushOptions{
 		RemoteName: remote,
 		RefSpecs: []config.RefSpec{
 			config.RefSpec(branch + ":" + branch),
 		},
 	}); err != nil {
-		return fmt.Errorf("go-git pushing %q %q to %q: %w", local, branch, remote, err)
+		if err := n.timeoutError(ctx, "git push"); err != nil {
+			return err
+		}
+
+		return ErrGit{Command: fmt.Sprintf("go-git push %s %s", remote, branch), Err: err}
 	}
 
 	return nil
 }
 
-var _ GitClient = &GoGit{}
+var (
+	_ GitClient      = &GoGit{}
+	_ GitRefResolver = &GoGit{}
+	_ GitDatedLogger = &GoGit{}
+)
 
 func (n *GoGit) Fetch(workspaceDir, ref string) error {
 	r, err := git.PlainOpen(workspaceDir)
@@ -332,7 +450,10 @@ func (n *GoGit) Fetch(workspaceDir, ref string) error {
 		return fmt.Errorf("go-git opening %q: %w", workspaceDir, err)
 	}
 
-	if err := r.Fetch(&git.FetchOptions{
+	ctx, cancel := n.context()
+	defer cancel()
+
+	if err := r.FetchContext(ctx, &git.FetchOptions{
 		// Apparently go-git's `fetch` doesn't automatically fetch all the remote branches without ref spec.
 		// That is, `go-git fetch` isn't the same as `go fetch` but `go-git fetch origin branch` is the same as
 		// `go fetch origin branch`.
@@ -340,24 +461,43 @@ func (n *GoGit) Fetch(workspaceDir, ref string) error {
 		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", ref, ref))},
 		RemoteName: "origin",
 	}); err != nil && err.Error() != "already up-to-date" {
-		return fmt.Errorf("go-git fetching %q: %w", workspaceDir, err)
+		if err := n.timeoutError(ctx, "git fetch"); err != nil {
+			return err
+		}
+
+		return ErrGit{Command: fmt.Sprintf("go-git fetch origin %s", ref), Err: err}
 	}
 
 	return nil
 }
 
 func (n *GoGit) Clone(rig, workspaceDir string) error {
-	_, err := git.PlainClone(workspaceDir, false, &git.CloneOptions{
+	ctx, cancel := n.context()
+	defer cancel()
+
+	_, err := git.PlainCloneContext(ctx, workspaceDir, false, &git.CloneOptions{
 		URL: rig,
 	})
 	if err != nil {
-		return fmt.Errorf("go-git cloning %q into %q: %w", rig, workspaceDir, err)
+		if err := n.timeoutError(ctx, "git clone"); err != nil {
+			return withRig(err, rig)
+		}
+
+		return ErrGit{Command: fmt.Sprintf("go-git clone %s %s", rig, workspaceDir), Err: err}
 	}
 
 	return nil
 }
 
 func (n *GoGit) Log(workspaceDir, filePath string) (string, error) {
+	return n.log(workspaceDir, filePath, false)
+}
+
+func (n *GoGit) LogWithDates(workspaceDir, filePath string) (string, error) {
+	return n.log(workspaceDir, filePath, true)
+}
+
+func (n *GoGit) log(workspaceDir, filePath string, withDates bool) (string, error) {
 	r, err := git.PlainOpen(workspaceDir)
 	if err != nil {
 		return "", fmt.Errorf("go-git opening %q: %w", workspaceDir, err)
@@ -367,7 +507,7 @@ func (n *GoGit) Log(workspaceDir, filePath string) (string, error) {
 		FileName: &filePath,
 	})
 	if err != nil {
-		return "", fmt.Errorf("go-git log %q %q: %w", workspaceDir, filePath, err)
+		return "", ErrGit{Command: fmt.Sprintf("go-git log -- %s", filePath), Err: err}
 	}
 
 	var gitLogOutput bytes.Buffer
@@ -381,12 +521,16 @@ func (n *GoGit) Log(workspaceDir, filePath string) (string, error) {
 			// `go-git log -- PATH` seems to return commits that doesn't have any object at PATH.
 			return skip
 		}
-		if _, err := gitLogOutput.Write([]byte(fmt.Sprintf("%s %s\n", commit.ID(), oneline))); err != nil {
+		line := fmt.Sprintf("%s %s\n", commit.ID(), oneline)
+		if withDates {
+			line = fmt.Sprintf("%s %d %s\n", commit.ID(), commit.Committer.When.Unix(), oneline)
+		}
+		if _, err := gitLogOutput.Write([]byte(line)); err != nil {
 			return fmt.Errorf("proessing commit %q: %w", commit.ID(), err)
 		}
 		return nil
 	}); err != nil && err != skip {
-		return "", fmt.Errorf("go-git interating log entry: %w", err)
+		return "", ErrGit{Command: fmt.Sprintf("go-git log -- %s", filePath), Err: err}
 	}
 
 	return gitLogOutput.String(), nil
@@ -400,12 +544,12 @@ func (n *GoGit) Show(workspaceDir, commitID, filePath string) (string, error) {
 
 	c, err := r.CommitObject(plumbing.NewHash(commitID))
 	if err != nil {
-		return "", fmt.Errorf("go-git showing %q: %w", commitID, err)
+		return "", ErrGit{Command: fmt.Sprintf("go-git show %s:%s", commitID, filePath), Err: err}
 	}
 
 	f, err := c.File(filePath)
 	if err != nil {
-		return "", fmt.Errorf("go-git file %q: %w", filePath, err)
+		return "", ErrGit{Command: fmt.Sprintf("go-git show %s:%s", commitID, filePath), Err: err}
 	}
 
 	contents, err := f.Contents()
@@ -415,3 +559,21 @@ func (n *GoGit) Show(workspaceDir, commitID, filePath string) (string, error) {
 
 	return contents, nil
 }
+
+func (n *GoGit) ResolveRef(dir string, ref string) (string, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", fmt.Errorf("go-git opening %q: %w", dir, err)
+	}
+
+	for _, rev := range []string{ref, "refs/remotes/origin/" + ref, "refs/tags/" + ref} {
+		var h *plumbing.Hash
+
+		h, err = r.ResolveRevision(plumbing.Revision(rev))
+		if err == nil {
+			return h.String(), nil
+		}
+	}
+
+	return "", ErrGit{Command: "go-git rev-parse " + ref, Err: err}
+}
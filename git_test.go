@@ -1,8 +1,11 @@
 package shoal
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -46,3 +49,105 @@ func testClient(t *testing.T, c GitClient) {
 		t.Fatalf("force checking out %s: %v", b, err)
 	}
 }
+
+func TestFetchUpdatesWorkspace(t *testing.T) {
+	rig := testRig(t, fooRevision("1.0.0"))
+	defer os.RemoveAll(rig)
+
+	for _, c := range []GitClient{&NativeGit{}, &GoGit{}} {
+		t.Run(fmt.Sprintf("%T", c), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "shoal-workspace-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			if err := c.Clone(rig, dir); err != nil {
+				t.Fatal(err)
+			}
+
+			branch, err := c.ShowOriginHeadBranch(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := fooRevision(fmt.Sprintf("1.1.0-%T", c))
+
+			commitFile(t, rig, "Food/foo.lua", want, "foo 1.1.0")
+
+			if err := c.Fetch(dir, branch); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := c.ForceCheckout(dir, branch); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ioutil.ReadFile(filepath.Join(dir, "Food", "foo.lua"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != want {
+				t.Errorf("want the fetched revision %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+// minimalGit implements only GitClient, like GitClients written before the optional interfaces were added.
+type minimalGit struct {
+	GitClient
+}
+
+func TestResolveRefOptional(t *testing.T) {
+	rig := testRig(t, fooRevision("1.0.0"))
+	defer os.RemoveAll(rig)
+
+	if _, err := resolveRef(&retryingGit{GitClient: &NativeGit{}}, rig, "HEAD"); err != nil {
+		t.Errorf("want the ref resolved through the retrying client, got %v", err)
+	}
+
+	if _, err := resolveRef(&retryingGit{GitClient: minimalGit{&NativeGit{}}}, rig, "HEAD"); err == nil {
+		t.Error("want error for the client without ResolveRef, got none")
+	}
+}
+
+func TestLogWithDatesOptional(t *testing.T) {
+	rig := testRig(t, fooRevision("1.0.0"), fooRevision("1.1.0"))
+	defer os.RemoveAll(rig)
+
+	for _, c := range []GitClient{&NativeGit{}, &GoGit{}} {
+		t.Run(fmt.Sprintf("%T", c), func(t *testing.T) {
+			log, err := c.Log(rig, "Food/foo.lua")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if lines := strings.Split(strings.TrimSpace(log), "\n"); len(lines) != 2 || !strings.HasSuffix(lines[0], " revision 1") || strings.Count(lines[0], " ") != 2 {
+				t.Errorf("want `<commit ID> <subject>` lines, got %q", log)
+			}
+
+			for _, g := range []GitClient{c, minimalGit{c}} {
+				dated, err := logWithDates(g, rig, "Food/foo.lua")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				lines := strings.Split(strings.TrimSpace(dated), "\n")
+				if len(lines) != 2 {
+					t.Fatalf("want 2 commits, got %q", dated)
+				}
+
+				items := strings.SplitN(lines[0], " ", 3)
+				if len(items) != 3 || items[2] != "revision 1" {
+					t.Fatalf("want `<commit ID> <time> <subject>`, got %q", lines[0])
+				}
+
+				if _, minimal := g.(minimalGit); (items[1] == "0") != minimal {
+					t.Errorf("%T: want the time only when the client logs dates, got %q", g, items[1])
+				}
+			}
+		})
+	}
+}
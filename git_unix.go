@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package shoal
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes the command the leader of a new process group, so that its children can be killed with it.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,17 @@
+package shoal
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing on Windows, where only the git process itself is killed on timeout.
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+
+	cmd.Process.Kill()
+}
@@ -6,10 +6,12 @@ require (
 	github.com/Masterminds/semver v1.5.0
 	github.com/fishworks/gofish v0.13.1-0.20200806145805-309ee2606318
 	github.com/go-git/go-git/v5 v5.1.0
+	github.com/mholt/archiver/v3 v3.3.0
+	github.com/sirupsen/logrus v1.5.0
 	github.com/yuin/gluamapper v0.0.0-20150323120927-d836955830e7
 	github.com/yuin/gopher-lua v0.0.0-20191220021717-ab39c6098bdb
 	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543
-	gopkg.in/yaml.v2 v2.2.4
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 replace github.com/fishworks/gofish => github.com/mumoshu/gofish v0.13.1-0.20200908031540-08d193d442ab
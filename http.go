@@ -0,0 +1,76 @@
+package shoal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// WithHTTP configures the proxy and the TLS settings used for downloading packages and for the go-git provider.
+// The non-empty settings, including InsecureSkipVerify set to false, take precedence over the ones in the config.
+func WithHTTP(h HTTP) Option {
+	return func(app *App) {
+		app.overrides.http = h
+	}
+}
+
+// newHTTPClient returns the HTTP client configured with the proxy and the TLS settings.
+func newHTTPClient(h HTTP) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if h.Proxy != "" {
+		u, err := url.Parse(h.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parsing proxy URL %q: %w", h.Proxy, err)
+		}
+
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	caBundle := h.CABundle
+	if caBundle == "" {
+		caBundle = os.Getenv("SHOAL_CA_BUNDLE")
+	}
+
+	if caBundle != "" || h.insecureSkipVerify() {
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: h.insecureSkipVerify(),
+		}
+	}
+
+	if caBundle != "" {
+		pem, err := ioutil.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("reading CA bundle: no certificates found in %s", caBundle)
+		}
+
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// installGoGitHTTPClient makes go-git use the HTTP client for http and https remotes.
+// go-git has no per-operation option for this in the version shoal uses, so the client is installed globally.
+func installGoGitHTTPClient(c *http.Client) {
+	t := githttp.NewClient(c)
+
+	client.InstallProtocol("https", t)
+	client.InstallProtocol("http", t)
+}
@@ -0,0 +1,90 @@
+package shoal
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "shoal-http-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	caBundle := filepath.Join(dir, "ca.pem")
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	if err := ioutil.WriteFile(caBundle, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	insecure, secure := true, false
+
+	testcases := []struct {
+		name    string
+		http    HTTP
+		wantErr bool
+	}{
+		{name: "untrusted", http: HTTP{}, wantErr: true},
+		{name: "ca bundle", http: HTTP{CABundle: caBundle}},
+		{name: "insecure", http: HTTP{InsecureSkipVerify: &insecure}},
+		{name: "secure", http: HTTP{InsecureSkipVerify: &secure}, wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := newHTTPClient(tc.http)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := c.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			if tc.wantErr != (err != nil) {
+				t.Errorf("want error: %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	// The config is applied by InitGitProvider
+	c := testApp(t, testRoot(t), Config{HTTP: HTTP{CABundle: caBundle}}).httpClient
+
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("want the CA bundle in the config to be trusted, got %v", err)
+	}
+	resp.Body.Close()
+
+	// The option takes precedence over the config
+	c = testApp(t, testRoot(t), Config{HTTP: HTTP{CABundle: filepath.Join(dir, "missing.pem")}}, WithHTTP(HTTP{CABundle: caBundle})).httpClient
+
+	resp, err = c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("want the CA bundle in the option to be trusted, got %v", err)
+	}
+	resp.Body.Close()
+
+	// The option disables InsecureSkipVerify enabled in the config
+	c = testApp(t, testRoot(t), Config{HTTP: HTTP{InsecureSkipVerify: &insecure}}, WithHTTP(HTTP{InsecureSkipVerify: &secure})).httpClient
+
+	resp, err = c.Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("want the certificate to be verified as the option disables InsecureSkipVerify")
+	}
+}
@@ -0,0 +1,316 @@
+package shoal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfig reads the config file at path along with all the files it includes, and returns the merged config.
+// Environment variables and template expressions in each file are expanded before decoding, as described in renderConfig.
+//
+// Included files are merged in order, followed by the including file itself, so that the including file
+// overrides what it includes. A later dependency overrides an earlier one with the same rig and food,
+// a later mirror overrides an earlier one with the same prefix,
+// and a later non-empty `rig`, `git.provider`, `http` or retry setting, food version, or helm plugin version overrides an earlier one.
+//
+// A file in a git repository is read with the git provider, mirrors, HTTP, retry and timeout settings in the file at path,
+// as a rig is.
+func LoadConfig(path string) (*Config, error) {
+	l := &configLoader{
+		clones:    map[string]string{},
+		lookupEnv: os.LookupEnv,
+	}
+
+	defer l.cleanup()
+
+	config, err := l.load(configSource{path: path}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, inFile(err, path)
+	}
+
+	return config, nil
+}
+
+// UnmarshalYAML accepts either a plain string for a local file, or a mapping.
+func (i *Include) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		i.Path = n.Value
+		return nil
+	}
+
+	if n.Kind == yaml.MappingNode {
+		// Custom unmarshalers don't inherit the strictness of the decoder
+		var errs []string
+
+		for j := 0; j+1 < len(n.Content); j += 2 {
+			switch k := n.Content[j]; k.Value {
+			case "path", "git", "ref":
+			default:
+				errs = append(errs, fmt.Sprintf("line %d: field %s not found in type shoal.Include", k.Line, k.Value))
+			}
+		}
+
+		if len(errs) > 0 {
+			return &yaml.TypeError{Errors: errs}
+		}
+	}
+
+	type include Include
+
+	return n.Decode((*include)(i))
+}
+
+// configSource is where a config file is read from.
+type configSource struct {
+	// git is the URL of the repository. Empty for a local file.
+	git  string
+	ref  string
+	path string
+}
+
+func (s configSource) String() string {
+	if s.git == "" {
+		return s.path
+	}
+
+	if s.ref == "" {
+		return fmt.Sprintf("%s//%s", s.git, s.path)
+	}
+
+	return fmt.Sprintf("%s//%s?ref=%s", s.git, s.path, s.ref)
+}
+
+// include returns the source of the file included from s.
+// A local include in a git repository is read from the same repository at the same ref.
+func (s configSource) include(inc Include) configSource {
+	if inc.Git != "" {
+		return configSource{git: inc.Git, ref: inc.Ref, path: inc.Path}
+	}
+
+	if s.git != "" {
+		return configSource{git: s.git, ref: s.ref, path: path.Join(path.Dir(s.path), inc.Path)}
+	}
+
+	if filepath.IsAbs(inc.Path) {
+		return configSource{path: inc.Path}
+	}
+
+	return configSource{path: filepath.Join(filepath.Dir(s.path), inc.Path)}
+}
+
+type configLoader struct {
+	// app reads the files in git repositories. It is configured by the root config
+	app *App
+
+	// clones maps git repository URLs to the temporary directories they are cloned into
+	clones map[string]string
+
+	lookupEnv func(string) (string, bool)
+}
+
+// load reads the config from src and merges the files it includes. stack is the chain of files including src.
+func (l *configLoader) load(src configSource, stack []configSource) (*Config, error) {
+	for _, s := range stack {
+		if s == src {
+			var chain []string
+
+			for _, s := range append(stack, src) {
+				chain = append(chain, s.String())
+			}
+
+			return nil, fmt.Errorf("circular include: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	data, err := l.read(src)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = renderConfig(data, l.lookupEnv)
+	if err != nil {
+		return nil, inFile(err, src.String())
+	}
+
+	config, err := parseConfig(data, src.String(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.app == nil {
+		// The root config decides how included files are read from git repositories, as it does for rigs
+		l.app, err = New(LogOutput(ioutil.Discard))
+		if err != nil {
+			return nil, err
+		}
+
+		if err := l.app.InitGitProvider(*config); err != nil {
+			return nil, inFile(err, src.String())
+		}
+	}
+
+	merged := &Config{}
+
+	for _, inc := range config.Include {
+		included, err := l.load(src.include(inc), append(stack, src))
+		if err != nil {
+			return nil, err
+		}
+
+		merged.merge(*included)
+	}
+
+	merged.merge(*config)
+
+	return merged, nil
+}
+
+func (l *configLoader) read(src configSource) ([]byte, error) {
+	if src.git == "" {
+		bs, err := ioutil.ReadFile(src.path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config: %w", err)
+		}
+
+		return bs, nil
+	}
+
+	dir, ok := l.clones[src.git]
+	if !ok {
+		tmp, err := ioutil.TempDir("", "shoal-include-")
+		if err != nil {
+			return nil, err
+		}
+
+		dir = filepath.Join(tmp, "repo")
+
+		if err := l.app.git.Clone(l.app.rewriteURL(src.git), dir); err != nil {
+			os.RemoveAll(tmp)
+
+			return nil, fmt.Errorf("cloning %s: %w", src.git, err)
+		}
+
+		l.clones[src.git] = dir
+	}
+
+	ref := src.ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	commitID, err := resolveRef(l.app.git, dir, ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolving ref %q in %s: %w", ref, src.git, err)
+	}
+
+	content, err := l.app.git.Show(dir, commitID, src.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", src, err)
+	}
+
+	return []byte(content), nil
+}
+
+func (l *configLoader) cleanup() {
+	for _, dir := range l.clones {
+		os.RemoveAll(filepath.Dir(dir))
+	}
+}
+
+// merge overrides the config with o. Includes are not merged, as they are already processed by the loader.
+func (c *Config) merge(o Config) {
+	if o.Git.Provider != "" {
+		c.Git.Provider = o.Git.Provider
+	}
+
+	if o.Rig != "" {
+		c.Rig = o.Rig
+	}
+
+	override := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+
+	override(&c.Foods.Helmfile, o.Foods.Helmfile)
+	override(&c.Foods.Helm, o.Foods.Helm)
+	override(&c.Foods.Kubectl, o.Foods.Kubectl)
+	override(&c.Foods.Eksctl, o.Foods.Eksctl)
+	override(&c.Helm.Plugins.Diff, o.Helm.Plugins.Diff)
+	override(&c.HTTP.Proxy, o.HTTP.Proxy)
+	override(&c.HTTP.CABundle, o.HTTP.CABundle)
+
+	if o.HTTP.InsecureSkipVerify != nil {
+		c.HTTP.InsecureSkipVerify = o.HTTP.InsecureSkipVerify
+	}
+
+	c.Git.Retry.merge(o.Git.Retry)
+	c.Download.Retry.merge(o.Download.Retry)
+
+	if o.Git.Timeout != 0 {
+		c.Git.Timeout = o.Git.Timeout
+	}
+
+	if o.Download.Timeout != 0 {
+		c.Download.Timeout = o.Download.Timeout
+	}
+
+	for food, version := range o.Foods.Others {
+		if c.Foods.Others == nil {
+			c.Foods.Others = map[string]string{}
+		}
+
+		c.Foods.Others[food] = version
+	}
+
+	for _, m := range o.Mirrors {
+		var found bool
+
+		for i := range c.Mirrors {
+			if c.Mirrors[i].Prefix == m.Prefix {
+				c.Mirrors[i] = m
+				found = true
+			}
+		}
+
+		if !found {
+			c.Mirrors = append(c.Mirrors, m)
+		}
+	}
+
+	c.Dependencies = mergeDependencies(c.Dependencies, o.Dependencies)
+}
+
+// mergeDependencies appends more to deps. A dependency in more replaces the one in deps with the same rig and food,
+// keeping its position.
+func mergeDependencies(deps, more []Dependency) []Dependency {
+	var merged []Dependency
+
+	index := map[[2]string]int{}
+
+	for _, d := range append(append([]Dependency{}, deps...), more...) {
+		key := [2]string{d.Rig, d.Food}
+
+		if i, ok := index[key]; ok {
+			merged[i] = d
+			continue
+		}
+
+		index[key] = len(merged)
+
+		merged = append(merged, d)
+	}
+
+	return merged
+}
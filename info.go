@@ -0,0 +1,171 @@
+package shoal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FoodInfo describes the version of the food that Sync would select for the config.
+type FoodInfo struct {
+	Rig          string `json:"rig"`
+	Food         string `json:"food"`
+	Constraint   string `json:"constraint,omitempty"`
+	Version      string `json:"version"`
+	FoodCommitID string `json:"foodCommitID"`
+
+	Description string `json:"description,omitempty"`
+	Homepage    string `json:"homepage,omitempty"`
+	License     string `json:"license,omitempty"`
+	Caveats     string `json:"caveats,omitempty"`
+
+	// InstalledVersion is the version installed in the current generation. Empty when the food isn't installed.
+	InstalledVersion string `json:"installedVersion,omitempty"`
+
+	Packages []PackageInfo `json:"packages"`
+}
+
+// PackageInfo describes a package of the food.
+type PackageInfo struct {
+	OS     string `json:"os"`
+	Arch   string `json:"arch"`
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+	// Target is true for the package of the target platform, the one Sync installs.
+	Target    bool           `json:"target"`
+	Resources []ResourceInfo `json:"resources,omitempty"`
+}
+
+// ResourceInfo describes a file in the package, and where it is installed.
+type ResourceInfo struct {
+	// Path is the path to the file in the package.
+	Path string `json:"path"`
+	// InstallPath is the path to the link to the file, relative to the root of the GoFish home, like `bin/helm`.
+	InstallPath string `json:"installPath"`
+	Executable  bool   `json:"executable,omitempty"`
+
+	// The following are set only for the package of the target platform.
+
+	// LinkPath is the path to the link in RootDir, like `.shoal/bin/helm`.
+	LinkPath string `json:"linkPath,omitempty"`
+	// LinkExists is true when the link points to BarrelPath, and the file exists.
+	// It is false for a link to the file of another version.
+	LinkExists bool `json:"linkExists,omitempty"`
+	// LinkTarget is the path the link points to. Empty when the link doesn't exist.
+	LinkTarget string `json:"linkTarget,omitempty"`
+	// BarrelPath is the path to the file unpacked from the package in RootDir.
+	BarrelPath string `json:"barrelPath,omitempty"`
+	// BarrelExists is true when the file has been unpacked.
+	BarrelExists bool `json:"barrelExists,omitempty"`
+}
+
+// Info resolves the food declared in the config as Sync does, and describes the selected version
+// along with which of its files exist in RootDir. It is useful for finding out why a binary is missing.
+// A food not declared in the config is read from the top-level rig without any constraint.
+func (a *App) Info(config Config, food string) (*FoodInfo, error) {
+	a.setEnv()
+
+	goos, goarch := a.platform()
+
+	d, err := config.dependency(food, goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := a.resolve(d.Rig, d.Food, d.Version, d.Vars)
+	if err != nil {
+		return nil, ErrDependency{Rig: d.Rig, Food: d.Food, Constraint: d.Version, Err: err}
+	}
+
+	f := version.food
+
+	info := &FoodInfo{
+		Rig:          d.Rig,
+		Food:         d.Food,
+		Constraint:   d.Version,
+		Version:      f.Version,
+		FoodCommitID: version.foodCommitID,
+		Description:  f.Description,
+		Homepage:     f.Homepage,
+		License:      f.License,
+		Caveats:      f.Caveats,
+	}
+
+	installedFoods, err := a.InstalledFoods()
+	if err != nil {
+		return nil, err
+	}
+
+	if installed, ok := installedFoods[f.Name]; ok {
+		info.InstalledVersion = installed.Version
+	}
+
+	target := f.GetPackage(goos, goarch)
+
+	for _, pkg := range f.Packages {
+		p := PackageInfo{
+			OS:     pkg.OS,
+			Arch:   pkg.Arch,
+			URL:    pkg.URL,
+			SHA256: pkg.SHA256,
+			Target: pkg == target,
+		}
+
+		for _, r := range pkg.Resources {
+			ri := ResourceInfo{
+				Path:        r.Path,
+				InstallPath: r.InstallPath,
+				Executable:  r.Executable,
+			}
+
+			if p.Target {
+				ri.LinkPath, err = linkPath(a.BinPath(), r)
+				if err != nil {
+					return nil, err
+				}
+
+				ri.BarrelPath = filepath.Join(barrelDir(&f, pkg), r.Path)
+
+				_, err = os.Stat(ri.BarrelPath)
+				ri.BarrelExists = err == nil
+
+				ri.LinkTarget, _ = os.Readlink(ri.LinkPath)
+				ri.LinkExists = ri.BarrelExists && ri.LinkTarget == ri.BarrelPath
+			}
+
+			p.Resources = append(p.Resources, ri)
+		}
+
+		info.Packages = append(info.Packages, p)
+	}
+
+	return info, nil
+}
+
+// dependency returns the dependency on the food declared in the config for the platform.
+// It defaults to the food in the top-level rig without any constraint, when the food isn't declared at all.
+func (config Config) dependency(food, os, arch string) (Dependency, error) {
+	var declared bool
+
+	for _, d := range config.dependencies() {
+		if d.Food != food {
+			continue
+		}
+
+		declared = true
+
+		if d, ok := d.forPlatform(os, arch); ok {
+			return d, nil
+		}
+	}
+
+	if declared {
+		return Dependency{}, fmt.Errorf("food %q is %s", food, skipReason(os, arch))
+	}
+
+	if config.Rig == "" {
+		return Dependency{}, fmt.Errorf("food %q is not declared in the config, and no top-level rig is set", food)
+	}
+
+	return Dependency{Rig: config.Rig, Food: food}, nil
+}
@@ -0,0 +1,105 @@
+package shoal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInfo(t *testing.T) {
+	rig := testRig(t, `food = {
+  name = "foo",
+  version = "1.0.0",
+  description = "Foo",
+  packages = {
+    { os = "linux", arch = "amd64", url = "https://example.com/foo-linux.tar.gz", sha256 = "abc", resources = { { path = "foo", installpath = "bin/foo", executable = true } } },
+    { os = "darwin", arch = "amd64", url = "https://example.com/foo-darwin.tar.gz", sha256 = "def", resources = { { path = "foo", installpath = "bin/foo", executable = true } } },
+  },
+}`)
+	defer os.RemoveAll(rig)
+
+	root := testRoot(t)
+
+	config := Config{Dependencies: []Dependency{{Rig: rig, Food: "foo", Version: "1.0.0"}}}
+
+	app := testApp(t, root, config, Target("linux", "amd64"))
+
+	// Unpacked, but not linked
+	barrel := filepath.Join(root, "Barrel", "foo", "1.0.0", "linux-amd64")
+
+	if err := os.MkdirAll(barrel, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(barrel, "foo"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := app.Info(config, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Version != "1.0.0" || info.Description != "Foo" || info.Rig != rig || info.FoodCommitID == "" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	if len(info.Packages) != 2 || !info.Packages[0].Target || info.Packages[1].Target {
+		t.Fatalf("want linux/amd64 to be the target, got %+v", info.Packages)
+	}
+
+	r := info.Packages[0].Resources[0]
+
+	if !r.BarrelExists || r.LinkExists || r.LinkPath != filepath.Join(root, "bin", "foo") {
+		t.Errorf("unexpected resource: %+v", r)
+	}
+
+	if r := info.Packages[1].Resources[0]; r.LinkPath != "" || r.BarrelPath != "" {
+		t.Errorf("want no paths for the non-target package, got %+v", r)
+	}
+
+	// A stale link to the file of another version
+	stale := filepath.Join(root, "Barrel", "foo", "0.9.0", "linux-amd64", "foo")
+
+	if err := os.MkdirAll(filepath.Dir(stale), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(stale, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	link := func(target string) ResourceInfo {
+		t.Helper()
+
+		os.Remove(r.LinkPath)
+
+		if err := os.Symlink(target, r.LinkPath); err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := app.Info(config, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return info.Packages[0].Resources[0]
+	}
+
+	if r := link(stale); r.LinkExists || r.LinkTarget != stale {
+		t.Errorf("want the link to another version not to count as linked, got %+v", r)
+	}
+
+	if r := link(r.BarrelPath); !r.LinkExists || r.LinkTarget != r.BarrelPath {
+		t.Errorf("want the link to be found, got %+v", r)
+	}
+
+	if _, err := app.Info(config, "bar"); err == nil {
+		t.Errorf("want error for an undeclared food without a top-level rig")
+	}
+}
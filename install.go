@@ -0,0 +1,223 @@
+package shoal
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fishworks/gofish"
+	"github.com/fishworks/gofish/pkg/home"
+	"github.com/mholt/archiver/v3"
+)
+
+// install downloads and unpacks the package into the barrel, and links its resources into binDir.
+//
+// This is a reorganized version of gofish's Food.Install that links resources into the given directory
+// instead of GOFISH_BINPATH, so that shoal can stage installs into a generation before making them visible.
+func (a *App) install(f *gofish.Food, pkg *gofish.Package, binDir string) error {
+	u, err := url.Parse(pkg.URL)
+	if err != nil {
+		return fmt.Errorf("could not parse package URL '%s' as a URL: %v", pkg.URL, err)
+	}
+
+	cachedFilePath, err := a.fetchPackage(f, pkg)
+	if err != nil {
+		return err
+	}
+
+	if f.PreInstallScript != "" {
+		cmd := exec.Command(f.PreInstallScript)
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+
+	if err := unpack(cachedFilePath, barrelDir(f, pkg), u.Path); err != nil {
+		return fmt.Errorf("unpacking %s: %w", cachedFilePath, err)
+	}
+
+	if err := link(f, pkg, binDir); err != nil {
+		return fmt.Errorf("linking %s %s: %w", f.Name, f.Version, err)
+	}
+
+	if f.PostInstallScript != "" {
+		cmd := exec.Command(f.PostInstallScript)
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+
+	if f.Caveats != "" {
+		a.logger.Printf("%s %s caveats:\n%s", f.Name, f.Version, f.Caveats)
+	}
+
+	return nil
+}
+
+// fetchPackage downloads the package into the cache unless it is already there, and verifies its sha256.
+// It returns the path to the cached file.
+func (a *App) fetchPackage(f *gofish.Food, pkg *gofish.Package) (string, error) {
+	cachedFilePath, err := cachedPackagePath(f, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachedFilePath), 0755); err != nil {
+		return "", err
+	}
+
+	if err := a.download(f, pkg, cachedFilePath); err != nil {
+		return "", err
+	}
+
+	if err := checksumVerifyPath(cachedFilePath, pkg.SHA256); err != nil {
+		// Remove the broken download so that the next attempt doesn't reuse it
+		os.Remove(cachedFilePath)
+
+		return "", fmt.Errorf("shasum verify check failed: %v", err)
+	}
+
+	return cachedFilePath, nil
+}
+
+// cachedPackagePath returns the path to the package in the download cache.
+func cachedPackagePath(f *gofish.Food, pkg *gofish.Package) (string, error) {
+	u, err := url.Parse(pkg.URL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse package URL '%s' as a URL: %v", pkg.URL, err)
+	}
+
+	return filepath.Join(home.Cache(), fmt.Sprintf("%s-%s-%s-%s%s", f.Name, f.Version, pkg.OS, pkg.Arch, getExtension(u.Path))), nil
+}
+
+// barrelDir returns the directory the package is unpacked into.
+// It is per platform, so that installing for another platform into the same root doesn't replace the files
+// the host links point to.
+func barrelDir(f *gofish.Food, pkg *gofish.Package) string {
+	return filepath.Join(home.Barrel(), f.Name, f.Version, pkg.OS+"-"+pkg.Arch)
+}
+
+// unpack extracts the archive into dest, replacing what was there.
+// The archive is extracted into a temporary directory next to dest first, so that
+// a failed extraction doesn't leave a half-populated barrel behind.
+func unpack(src, dest, urlPath string) error {
+	parent := filepath.Dir(dest)
+
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempDir(parent, "."+filepath.Base(dest)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := unarchiveOrCopy(src, tmp, urlPath); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, dest)
+}
+
+func unarchiveOrCopy(src, dest, urlPath string) error {
+	// check and see if it can be unarchived by archiver
+	if _, err := archiver.ByExtension(src); err == nil {
+		return archiver.Unarchive(src, dest)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(filepath.Join(dest, filepath.Base(urlPath)))
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// link creates links in binDir to the package's resources in the barrel, replacing existing ones.
+func link(f *gofish.Food, pkg *gofish.Package, binDir string) error {
+	for _, r := range pkg.Resources {
+		destPath, err := linkPath(binDir, r)
+		if err != nil {
+			return err
+		}
+
+		srcPath := filepath.Join(barrelDir(f, pkg), r.Path)
+
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return err
+		}
+
+		if r.Executable {
+			if err := os.Chmod(srcPath, 0755); err != nil {
+				return err
+			}
+		}
+
+		if err := os.RemoveAll(destPath); err != nil {
+			return err
+		}
+
+		if err := os.Symlink(srcPath, destPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// linkPath returns the path to the link to the resource in binDir.
+func linkPath(binDir string, r *gofish.Resource) (string, error) {
+	// We assume every Food's InstallPath begins with `bin/` (`bin\\` on Windows), as gofish does
+	installPath, err := filepath.Rel("bin", r.InstallPath)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(binDir, installPath), nil
+}
+
+func getExtension(path string) string {
+	urlParts := strings.Split(path, "/")
+	parts := strings.Split(urlParts[len(urlParts)-1], ".")
+	if len(parts) < 2 {
+		return filepath.Ext(path)
+	}
+	return "." + strings.Join([]string{parts[len(parts)-2], parts[len(parts)-1]}, ".")
+}
+
+func checksumVerifyPath(path string, checksum string) error {
+	hasher := sha256.New()
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return err
+	}
+
+	actualChecksum := fmt.Sprintf("%x", hasher.Sum(nil))
+	if strings.Compare(actualChecksum, strings.ToLower(checksum)) != 0 {
+		return fmt.Errorf("checksums differ for %s: expected '%s', got '%s'", path, checksum, actualChecksum)
+	}
+	return nil
+}
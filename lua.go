@@ -0,0 +1,120 @@
+package shoal
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fishworks/gofish"
+	"github.com/yuin/gluamapper"
+	"github.com/yuin/gopher-lua"
+)
+
+const (
+	defaultFoodTimeout = 5 * time.Second
+
+	// foodRegistryMaxSize caps the Lua value stack, so that a runaway recursion fails instead of eating the memory.
+	foodRegistryMaxSize = 256 * 1024
+)
+
+// WithFoodTimeout sets the maximum duration of evaluating each Lua food definition. Defaults to 5s.
+func WithFoodTimeout(d time.Duration) Option {
+	return func(app *App) {
+		app.foodTimeout = d
+	}
+}
+
+// foodLibs are the Lua libraries available to food definitions.
+// os, io, package and debug are left out so that a food definition can't touch the filesystem or run commands.
+var foodLibs = []struct {
+	name string
+	open lua.LGFunction
+}{
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+	{lua.CoroutineLibName, lua.OpenCoroutine},
+}
+
+// unsafeBaseFuncs are the functions of the base library that read files or load modules.
+var unsafeBaseFuncs = []string{"dofile", "loadfile", "require", "module", "_printregs"}
+
+// newFoodState returns a Lua state with only the libraries that are safe for evaluating untrusted food definitions.
+func newFoodState() *lua.LState {
+	l := lua.NewState(lua.Options{
+		SkipOpenLibs:    true,
+		RegistryMaxSize: foodRegistryMaxSize,
+	})
+
+	for _, lib := range foodLibs {
+		l.Push(l.NewFunction(lib.open))
+		l.Push(lua.LString(lib.name))
+		l.Call(1, 0)
+	}
+
+	for _, f := range unsafeBaseFuncs {
+		l.SetGlobal(f, lua.LNil)
+	}
+
+	return l
+}
+
+// newShoalTable returns the `shoal` global exposed to food definitions.
+// It tells the target platform, the version of shoal, and the vars given to the dependency.
+func newShoalTable(l *lua.LState, os, arch string, vars map[string]string) *lua.LTable {
+	t := l.NewTable()
+
+	t.RawSetString("os", lua.LString(os))
+	t.RawSetString("arch", lua.LString(arch))
+	t.RawSetString("version", lua.LString(Version))
+
+	v := l.NewTable()
+
+	for k, val := range vars {
+		v.RawSetString(k, lua.LString(val))
+	}
+
+	t.RawSetString("vars", v)
+
+	return t
+}
+
+// evalFood runs the Lua food definition in a sandbox for the platform and returns the food it defines.
+// Some historical revisions of rigs fail to evaluate, like ones with syntax errors, which callers report as rotten.
+// The evaluation is aborted with ErrTimeout when it exceeds the food timeout, so that an infinite loop can't hang shoal.
+func (a *App) evalFood(luaScript, os, arch string, vars map[string]string) (gofish.Food, error) {
+	var food gofish.Food
+
+	l := newFoodState()
+	defer l.Close()
+
+	l.SetGlobal("shoal", newShoalTable(l, os, arch, vars))
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.foodTimeout)
+	defer cancel()
+
+	l.SetContext(ctx)
+
+	if err := l.DoString(luaScript); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return food, ErrTimeout{Operation: "evaluating food", Timeout: a.foodTimeout}
+		}
+		return food, fmt.Errorf("executing lua: %w", err)
+	}
+
+	name := strings.ToLower(reflect.TypeOf(food).Name())
+
+	t, ok := l.GetGlobal(name).(*lua.LTable)
+	if !ok {
+		return food, fmt.Errorf("reading lua execution result: %q is not a table", name)
+	}
+
+	if err := gluamapper.Map(t, &food); err != nil {
+		return food, fmt.Errorf("reading lua execution result: %w", err)
+	}
+
+	return food, nil
+}
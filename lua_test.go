@@ -0,0 +1,127 @@
+package shoal
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvalFood(t *testing.T) {
+	app, err := New(LogOutput(ioutil.Discard), WithFoodTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name        string
+		script      string
+		wantTimeout bool
+		wantErr     string
+	}{
+		{
+			name:   "valid",
+			script: `local v = string.format("%d.%d.%d", 1, 2, 3); food = { name = "foo", version = v }`,
+		},
+		{
+			name:    "syntax error",
+			script:  `food = {`,
+			wantErr: "syntax error",
+		},
+		{
+			name:    "os",
+			script:  `os.execute("touch /tmp/shoal-pwned"); food = { name = "foo" }`,
+			wantErr: "attempt to index a non-table object",
+		},
+		{
+			name:    "io",
+			script:  `io.open("/etc/passwd"); food = { name = "foo" }`,
+			wantErr: "attempt to index a non-table object",
+		},
+		{
+			name:    "dofile",
+			script:  `dofile("/etc/passwd"); food = { name = "foo" }`,
+			wantErr: "attempt to call a non-function object",
+		},
+		{
+			name:    "require",
+			script:  `local os = require("os"); food = { name = "foo" }`,
+			wantErr: "attempt to call a non-function object",
+		},
+		{
+			name:        "infinite loop",
+			script:      `while true do end`,
+			wantTimeout: true,
+		},
+		{
+			name:    "not a table",
+			script:  `food = "foo"`,
+			wantErr: "not a table",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			food, err := app.evalFood(tc.script, "linux", "amd64", nil)
+
+			var timeout ErrTimeout
+
+			switch {
+			case tc.wantTimeout:
+				if !errors.As(err, &timeout) {
+					t.Fatalf("want ErrTimeout, got %v", err)
+				}
+			case tc.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tc.wantErr, err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			default:
+				if food.Name != "foo" || food.Version != "1.2.3" {
+					t.Errorf("unexpected food: %+v", food)
+				}
+			}
+		})
+	}
+}
+
+func TestEvalFoodShoalGlobals(t *testing.T) {
+	app, err := New(LogOutput(ioutil.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script := `
+local variant = ""
+if shoal.vars.fips == "true" then
+  variant = "-fips"
+end
+food = {
+  name = "foo",
+  version = "1.0.0",
+  packages = {
+    {
+      os = shoal.os,
+      arch = shoal.arch,
+      url = "https://example.com/foo-" .. shoal.os .. "-" .. shoal.arch .. variant .. ".tar.gz",
+    },
+  },
+}
+`
+
+	food, err := app.evalFood(script, "linux", "arm64", map[string]string{"fips": "true"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkg := food.GetPackage("linux", "arm64")
+	if pkg == nil {
+		t.Fatalf("want package for linux/arm64, got %+v", food.Packages)
+	}
+
+	if want := "https://example.com/foo-linux-arm64-fips.tar.gz"; pkg.URL != want {
+		t.Errorf("want URL %q, got %q", want, pkg.URL)
+	}
+}
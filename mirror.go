@@ -0,0 +1,31 @@
+package shoal
+
+import (
+	"strings"
+)
+
+// WithMirrors makes shoal download packages and clone rigs from the mirrors.
+// They take precedence over the mirrors of the same prefix in the config.
+func WithMirrors(mirrors ...Mirror) Option {
+	return func(app *App) {
+		app.overrides.mirrors = append(app.overrides.mirrors, mirrors...)
+	}
+}
+
+// rewriteURL returns the URL with its prefix replaced by the mirror with the longest matching prefix.
+// The URL is returned as-is when no mirror matches.
+func (a *App) rewriteURL(u string) string {
+	var found *Mirror
+
+	for i, m := range a.mirrors {
+		if strings.HasPrefix(u, m.Prefix) && (found == nil || len(m.Prefix) > len(found.Prefix)) {
+			found = &a.mirrors[i]
+		}
+	}
+
+	if found == nil {
+		return u
+	}
+
+	return found.URL + strings.TrimPrefix(u, found.Prefix)
+}
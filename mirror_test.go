@@ -0,0 +1,39 @@
+package shoal
+
+import (
+	"testing"
+)
+
+func TestRewriteURL(t *testing.T) {
+	// The mirror given as an option takes precedence over the one of the same prefix in the config
+	app := testApp(t, testRoot(t), Config{
+		Mirrors: []Mirror{
+			{Prefix: "https://github.com/", URL: "https://artifactory.example.com/github/"},
+			{Prefix: "https://github.com/helm/", URL: "https://helm-config.example.com/"},
+		},
+	}, WithMirrors(Mirror{Prefix: "https://github.com/helm/", URL: "https://helm.example.com/"}))
+
+	testcases := []struct {
+		url  string
+		want string
+	}{
+		{
+			url:  "https://github.com/fishworks/fish-food",
+			want: "https://artifactory.example.com/github/fishworks/fish-food",
+		},
+		{
+			url:  "https://github.com/helm/helm/releases/download/v3.3.0/helm.tar.gz",
+			want: "https://helm.example.com/helm/releases/download/v3.3.0/helm.tar.gz",
+		},
+		{
+			url:  "https://get.helm.sh/helm-v3.3.0-linux-amd64.tar.gz",
+			want: "https://get.helm.sh/helm-v3.3.0-linux-amd64.tar.gz",
+		},
+	}
+
+	for _, tc := range testcases {
+		if got := app.rewriteURL(tc.url); got != tc.want {
+			t.Errorf("%s: want %s, got %s", tc.url, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package shoal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/fishworks/gofish"
+	"github.com/fishworks/gofish/pkg/home"
+)
+
+// PlanAction describes what Sync would do to a dependency compared to what is currently installed.
+type PlanAction string
+
+const (
+	PlanActionInstall   PlanAction = "install"
+	PlanActionUpgrade   PlanAction = "upgrade"
+	PlanActionDowngrade PlanAction = "downgrade"
+	PlanActionReinstall PlanAction = "reinstall"
+	PlanActionNoop      PlanAction = "no-op"
+	// PlanActionSkip is for dependencies whose `os` or `arch` doesn't match the target platform.
+	PlanActionSkip PlanAction = "skip"
+)
+
+// Plan is the result of resolving every dependency in a config without installing anything.
+type Plan struct {
+	Dependencies []PlannedDependency
+}
+
+// PlannedDependency is the resolution result for a single dependency.
+type PlannedDependency struct {
+	Rig        string
+	Food       string
+	Constraint string
+
+	// Version is the food version selected by the constraint.
+	Version string
+	// FoodCommitID is the ID of the rig commit the food definition was read from.
+	FoodCommitID string
+	// URL and SHA256 describe the package for the target platform.
+	URL    string
+	SHA256 string
+
+	// InstalledVersion is the version currently linked into the bin dir, or empty if there is none.
+	InstalledVersion string
+	Action           PlanAction
+	// Reason describes why the dependency is skipped.
+	Reason string
+}
+
+// Plan resolves all the dependencies declared in the config and reports what Sync would do for each,
+// without downloading or installing any package.
+// In the keep-going mode, the plan for the successfully resolved dependencies is returned along with the error.
+func (a *App) Plan(config Config) (*Plan, error) {
+	a.setEnv()
+
+	var (
+		plan Plan
+		errs ErrDependencies
+	)
+
+	goos, goarch := a.platform()
+
+	for _, d := range config.dependencies() {
+		d, ok := d.forPlatform(goos, goarch)
+		if !ok {
+			plan.Dependencies = append(plan.Dependencies, PlannedDependency{
+				Rig:        d.Rig,
+				Food:       d.Food,
+				Constraint: d.Version,
+				Action:     PlanActionSkip,
+				Reason:     skipReason(goos, goarch),
+			})
+
+			continue
+		}
+
+		p, err := a.planDependency(d)
+		if err != nil {
+			if !a.keepGoing {
+				return nil, err
+			}
+
+			errs = append(errs, ErrDependency{Rig: d.Rig, Food: d.Food, Constraint: d.Version, Err: err})
+
+			continue
+		}
+
+		plan.Dependencies = append(plan.Dependencies, *p)
+	}
+
+	if len(errs) > 0 {
+		return &plan, errs
+	}
+
+	return &plan, nil
+}
+
+func (a *App) planDependency(d Dependency) (*PlannedDependency, error) {
+	version, err := a.resolve(d.Rig, d.Food, d.Version, d.Vars)
+	if err != nil {
+		return nil, err
+	}
+
+	f := version.food
+
+	p := &PlannedDependency{
+		Rig:          d.Rig,
+		Food:         d.Food,
+		Constraint:   d.Version,
+		Version:      f.Version,
+		FoodCommitID: version.foodCommitID,
+	}
+
+	pkg, err := a.getPackage(&f)
+	if err != nil {
+		return nil, err
+	}
+
+	p.URL = pkg.URL
+	p.SHA256 = pkg.SHA256
+
+	installedFoods, err := a.InstalledFoods()
+	if err != nil {
+		return nil, err
+	}
+
+	var upToDate bool
+
+	if installed, ok := installedFoods[f.Name]; ok {
+		p.InstalledVersion = installed.Version
+		upToDate = !a.force && installed.upToDate(&f, pkg, a.BinPath())
+	} else {
+		// The food might have been installed by a version of shoal that didn't record installed foods
+		p.InstalledVersion = installedVersion(&f, pkg)
+	}
+
+	p.Action, err = planAction(p.InstalledVersion, f.Version, upToDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func planAction(installed, desired string, upToDate bool) (PlanAction, error) {
+	if installed == "" {
+		return PlanActionInstall, nil
+	}
+
+	if upToDate {
+		return PlanActionNoop, nil
+	}
+
+	// The same version is reinstalled when it's broken or forced
+	if installed == desired {
+		return PlanActionReinstall, nil
+	}
+
+	iv, err := semver.NewVersion(installed)
+	if err != nil {
+		return "", fmt.Errorf("parsing installed version %q as semver: %w", installed, err)
+	}
+
+	dv, err := semver.NewVersion(desired)
+	if err != nil {
+		return "", fmt.Errorf("parsing %q as semver: %w", desired, err)
+	}
+
+	switch c := dv.Compare(iv); {
+	case c > 0:
+		return PlanActionUpgrade, nil
+	case c < 0:
+		return PlanActionDowngrade, nil
+	default:
+		return PlanActionReinstall, nil
+	}
+}
+
+// installedVersion returns the version of the food the package's resources are currently linked to,
+// by reading the symlinks in the bin dir.
+// It returns an empty string when no resource is linked into the food's barrel.
+func installedVersion(f *gofish.Food, pkg *gofish.Package) string {
+	barrelDir := filepath.Join(home.Barrel(), f.Name) + string(os.PathSeparator)
+
+	for _, r := range pkg.Resources {
+		p, err := linkPath(home.BinPath(), r)
+		if err != nil {
+			continue
+		}
+
+		link, err := os.Readlink(p)
+		if err != nil {
+			continue
+		}
+
+		if !strings.HasPrefix(link, barrelDir) {
+			continue
+		}
+
+		rel := strings.TrimPrefix(link, barrelDir)
+
+		return strings.SplitN(rel, string(os.PathSeparator), 2)[0]
+	}
+
+	return ""
+}
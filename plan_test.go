@@ -0,0 +1,31 @@
+package shoal
+
+import (
+	"testing"
+)
+
+func TestPlanAction(t *testing.T) {
+	testcases := []struct {
+		installed, desired string
+		upToDate           bool
+		want               PlanAction
+	}{
+		{installed: "", desired: "3.3.0", want: PlanActionInstall},
+		{installed: "3.2.4", desired: "3.3.0", want: PlanActionUpgrade},
+		{installed: "3.3.0", desired: "3.2.4", want: PlanActionDowngrade},
+		{installed: "3.3.0", desired: "3.3.0", want: PlanActionReinstall},
+		{installed: "3.3.0", desired: "3.3.0", upToDate: true, want: PlanActionNoop},
+		{installed: "v3.3.0", desired: "3.3.0", want: PlanActionReinstall},
+	}
+
+	for _, tc := range testcases {
+		got, err := planAction(tc.installed, tc.desired, tc.upToDate)
+		if err != nil {
+			t.Fatalf("planAction(%q, %q): %v", tc.installed, tc.desired, err)
+		}
+
+		if got != tc.want {
+			t.Errorf("planAction(%q, %q): want %q, got %q", tc.installed, tc.desired, tc.want, got)
+		}
+	}
+}
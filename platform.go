@@ -0,0 +1,132 @@
+package shoal
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/fishworks/gofish"
+	"gopkg.in/yaml.v3"
+)
+
+// Target makes shoal install the packages for the platform, instead of the one shoal is running on.
+// The `os` and `arch` selectors and the per-platform versions of dependencies are evaluated against it, too.
+func Target(os, arch string) Option {
+	return func(app *App) {
+		app.os = os
+		app.arch = arch
+	}
+}
+
+// platform returns the target OS and arch, defaulting to the ones shoal is running on.
+func (a *App) platform() (string, string) {
+	os, arch := a.os, a.arch
+
+	if os == "" {
+		os = runtime.GOOS
+	}
+
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+
+	return os, arch
+}
+
+// isHost returns true when the target platform is the one shoal is running on.
+func (a *App) isHost() bool {
+	os, arch := a.platform()
+
+	return os == runtime.GOOS && arch == runtime.GOARCH
+}
+
+// getPackage returns the package of the food for the target platform.
+func (a *App) getPackage(f *gofish.Food) (*gofish.Package, error) {
+	os, arch := a.platform()
+
+	pkg := f.GetPackage(os, arch)
+	if pkg == nil {
+		return nil, fmt.Errorf("food %q does not support the target platform (%s/%s)", f.Name, os, arch)
+	}
+
+	return pkg, nil
+}
+
+// UnmarshalYAML accepts either a plain string or a sequence of strings.
+func (s *Selector) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*s = Selector{n.Value}
+		return nil
+	}
+
+	var values []string
+
+	if err := n.Decode(&values); err != nil {
+		return err
+	}
+
+	*s = values
+
+	return nil
+}
+
+func (s Selector) matches(v string) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	for _, sv := range s {
+		if sv == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forPlatform returns the dependency with the version for the platform,
+// or false when the dependency isn't for the platform.
+func (d Dependency) forPlatform(os, arch string) (Dependency, bool) {
+	if !d.OS.matches(os) || !d.Arch.matches(arch) {
+		return d, false
+	}
+
+	if v, ok := d.Versions[os+"/"+arch]; ok {
+		d.Version = v
+	} else if v, ok := d.Versions[os]; ok {
+		d.Version = v
+	}
+
+	return d, true
+}
+
+// skipReason describes why the dependency isn't installed on the platform.
+func skipReason(os, arch string) string {
+	return fmt.Sprintf("not for %s/%s", os, arch)
+}
+
+// skipDependency reports that the dependency isn't installed on the platform.
+func (a *App) skipDependency(d Dependency, os, arch string) {
+	reason := skipReason(os, arch)
+
+	a.logger.Printf("skipping %s: %s", d.Food, reason)
+
+	a.emit(Event{
+		Type:       EventSkipped,
+		Rig:        d.Rig,
+		Food:       d.Food,
+		Constraint: d.Version,
+		Reason:     reason,
+	})
+}
+
+// skipHelmDiff reports that the helm-diff plugin isn't installed.
+func (a *App) skipHelmDiff(version, reason string) {
+	a.logger.Printf("skipping helm-diff: %s", reason)
+
+	a.emit(Event{
+		Type:    EventSkipped,
+		Food:    "helm-diff",
+		Version: version,
+		Reason:  reason,
+	})
+}
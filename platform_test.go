@@ -0,0 +1,94 @@
+package shoal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDependencyForPlatform(t *testing.T) {
+	d := Dependency{
+		Rig:     "r",
+		Food:    "kind",
+		Version: ">= 0.8",
+		OS:      Selector{"linux", "darwin"},
+		Versions: map[string]string{
+			"darwin":      "0.9.0",
+			"linux/arm64": "0.8.1",
+		},
+	}
+
+	testcases := []struct {
+		os, arch string
+		want     string
+		ok       bool
+	}{
+		{os: "linux", arch: "amd64", want: ">= 0.8", ok: true},
+		{os: "linux", arch: "arm64", want: "0.8.1", ok: true},
+		{os: "darwin", arch: "amd64", want: "0.9.0", ok: true},
+		{os: "windows", arch: "amd64", ok: false},
+	}
+
+	for _, tc := range testcases {
+		got, ok := d.forPlatform(tc.os, tc.arch)
+		if ok != tc.ok {
+			t.Errorf("%s/%s: want %v, got %v", tc.os, tc.arch, tc.ok, ok)
+			continue
+		}
+
+		if ok && got.Version != tc.want {
+			t.Errorf("%s/%s: want version %q, got %q", tc.os, tc.arch, tc.want, got.Version)
+		}
+	}
+}
+
+func TestSyncForeignPlatform(t *testing.T) {
+	host := runtime.GOOS + "/" + runtime.GOARCH
+
+	foreign, foreignOS, foreignArch := "darwin/arm64", "darwin", "arm64"
+	if host == foreign {
+		foreign, foreignOS, foreignArch = "linux/amd64", "linux", "amd64"
+	}
+
+	packages := newTestPackages(t)
+
+	rig := testRig(t, packages.food(t, "foo", "1.0.0", host, foreign))
+	defer os.RemoveAll(rig)
+
+	root := testRoot(t)
+
+	config := Config{Dependencies: []Dependency{{Rig: rig, Food: "foo", Version: "1.0.0"}}}
+
+	hostApp := testApp(t, root, config)
+
+	if err := hostApp.Sync(config); err != nil {
+		t.Fatal(err)
+	}
+
+	hostGen, err := hostApp.currentGeneration()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testApp(t, root, config, Target(foreignOS, foreignArch)).Sync(config); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := readLink(t, filepath.Join(root, "bin"), "foo"), packageContent("foo", "1.0.0", foreign); got != want {
+		t.Errorf("want the foreign binary to be current, got %q", got)
+	}
+
+	// The links of the host generation still point to the host binaries
+	if got, want := readLink(t, hostGen.binDir(), "foo"), packageContent("foo", "1.0.0", host); got != want {
+		t.Errorf("want the host binary, got %q", got)
+	}
+
+	if _, err := hostApp.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := readLink(t, filepath.Join(root, "bin"), "foo"), packageContent("foo", "1.0.0", host); got != want {
+		t.Errorf("want the host binary after rollback, got %q", got)
+	}
+}
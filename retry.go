@@ -0,0 +1,237 @@
+package shoal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryBackoff    = time.Second
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+// RetryPolicy configures how many times and how often a failed git operation or download is retried.
+// Zero values are replaced with the defaults.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts, including the first one. Defaults to 3. Set 1 to disable retries.
+	Attempts int `yaml:"attempts,omitempty"`
+	// Backoff is the delay before the first retry, doubled for each subsequent retry. Defaults to 1s.
+	// A random jitter of up to half the delay is subtracted, so that concurrent clients don't retry at once.
+	Backoff time.Duration `yaml:"backoff,omitempty"`
+	// MaxBackoff caps the delay. Defaults to 30s.
+	MaxBackoff time.Duration `yaml:"maxBackoff,omitempty"`
+
+	// Retryable decides if the error is transient and worth retrying.
+	// Defaults to retrying network errors, 5xx and 429 responses for downloads,
+	// and failed git clones, fetches and remote queries except for missing repositories and authentication failures.
+	Retryable func(error) bool `yaml:"-"`
+}
+
+// WithGitRetry sets the retry policy for git operations that access remotes, like clone and fetch.
+// Its non-zero fields take precedence over the ones in the config.
+func WithGitRetry(p RetryPolicy) Option {
+	return func(app *App) {
+		app.overrides.gitRetry = p
+	}
+}
+
+// WithDownloadRetry sets the retry policy for package downloads.
+// Its non-zero fields take precedence over the ones in the config.
+func WithDownloadRetry(p RetryPolicy) Option {
+	return func(app *App) {
+		app.overrides.downloadRetry = p
+	}
+}
+
+func (p RetryPolicy) withDefaults(retryable func(error) bool) RetryPolicy {
+	if p.Attempts == 0 {
+		p.Attempts = defaultRetryAttempts
+	}
+
+	if p.Backoff == 0 {
+		p.Backoff = defaultRetryBackoff
+	}
+
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = defaultRetryMaxBackoff
+	}
+
+	if p.Retryable == nil {
+		p.Retryable = retryable
+	}
+
+	return p
+}
+
+// IsZero returns true when no field is set, so that an unset policy is omitted from the YAML.
+func (p RetryPolicy) IsZero() bool {
+	return p.Attempts == 0 && p.Backoff == 0 && p.MaxBackoff == 0 && p.Retryable == nil
+}
+
+// merge overrides the policy with the non-zero fields of o.
+func (p *RetryPolicy) merge(o RetryPolicy) {
+	if o.Attempts != 0 {
+		p.Attempts = o.Attempts
+	}
+
+	if o.Backoff != 0 {
+		p.Backoff = o.Backoff
+	}
+
+	if o.MaxBackoff != 0 {
+		p.MaxBackoff = o.MaxBackoff
+	}
+
+	if o.Retryable != nil {
+		p.Retryable = o.Retryable
+	}
+}
+
+// delay returns the delay before the retry following the attempt.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	if half := int64(d / 2); half > 0 {
+		d -= time.Duration(rand.Int63n(half))
+	}
+
+	return d
+}
+
+// retry calls f until it succeeds, the error isn't retryable, or the attempts are exhausted.
+// Each retry is logged and emitted as a Retrying event based on ev.
+func (a *App) retry(p RetryPolicy, op string, ev Event, f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= p.Attempts || !p.Retryable(err) {
+			return err
+		}
+
+		delay := p.delay(attempt)
+
+		a.logger.Printf("%s failed (attempt %d/%d), retrying in %s: %v", op, attempt, p.Attempts, delay, err)
+
+		ev.Type = EventRetrying
+		ev.Reason = op
+		ev.Attempt = attempt
+		ev.Delay = delay
+		ev.Err = err
+
+		a.emit(ev)
+
+		time.Sleep(delay)
+	}
+}
+
+func isRetryableDownloadError(err error) bool {
+	var timeout ErrTimeout
+
+	if errors.As(err, &timeout) {
+		return true
+	}
+
+	var status ErrHTTPStatus
+
+	if errors.As(err, &status) {
+		return status.StatusCode >= 500 || status.StatusCode == 429
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func isRetryableGitError(err error) bool {
+	if errors.Is(err, transport.ErrRepositoryNotFound) ||
+		errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) {
+		return false
+	}
+
+	var timeout ErrTimeout
+
+	if errors.As(err, &timeout) {
+		return true
+	}
+
+	var gitErr ErrGit
+
+	if !errors.As(err, &gitErr) {
+		return false
+	}
+
+	for _, permanent := range []string{"not found", "does not exist", "does not appear to be a git repository", "Authentication failed"} {
+		if strings.Contains(gitErr.Output, permanent) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// retryingGit retries the operations of the GitClient that access remotes.
+type retryingGit struct {
+	GitClient
+
+	app    *App
+	policy RetryPolicy
+}
+
+func (g *retryingGit) Clone(repo, dir string) error {
+	return g.app.retry(g.policy, "git clone "+repo, Event{Rig: repo}, func() error {
+		err := g.GitClient.Clone(repo, dir)
+		if err != nil {
+			// Remove the partial clone so that the next attempt can clone into the same dir
+			os.RemoveAll(dir)
+		}
+
+		return err
+	})
+}
+
+// ResolveRef doesn't access remotes. It is defined only to expose the GitRefResolver of the wrapped GitClient.
+func (g *retryingGit) ResolveRef(dir, ref string) (string, error) {
+	return resolveRef(g.GitClient, dir, ref)
+}
+
+// LogWithDates doesn't access remotes. It is defined only to expose the GitDatedLogger of the wrapped GitClient.
+func (g *retryingGit) LogWithDates(dir, file string) (string, error) {
+	return logWithDates(g.GitClient, dir, file)
+}
+
+func (g *retryingGit) Fetch(dir, ref string) error {
+	return g.app.retry(g.policy, fmt.Sprintf("git fetch origin %s in %s", ref, dir), Event{}, func() error {
+		return g.GitClient.Fetch(dir, ref)
+	})
+}
+
+func (g *retryingGit) ShowOriginHeadBranch(dir string) (string, error) {
+	var b string
+
+	err := g.app.retry(g.policy, "git remote show origin in "+dir, Event{}, func() error {
+		var err error
+
+		b, err = g.GitClient.ShowOriginHeadBranch(dir)
+
+		return err
+	})
+
+	return b, err
+}
@@ -0,0 +1,216 @@
+package shoal
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fishworks/gofish"
+)
+
+// flakyServer fails the first `failures` requests with the status, and serves the content afterwards.
+type flakyServer struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	requests int
+	content  []byte
+}
+
+func (s *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+
+	if s.requests <= s.failures {
+		w.WriteHeader(s.status)
+		return
+	}
+
+	w.Write(s.content)
+}
+
+func TestDownloadRetry(t *testing.T) {
+	content := []byte("#!/bin/sh\necho foo\n")
+
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+	testcases := []struct {
+		name         string
+		option       RetryPolicy
+		config       RetryPolicy
+		failures     int
+		status       int
+		wantErr      bool
+		wantRequests int
+		wantRetries  int
+	}{
+		{name: "transient", option: policy, failures: 2, status: http.StatusServiceUnavailable, wantRequests: 3, wantRetries: 2},
+		{name: "exhausted", option: policy, failures: 3, status: http.StatusBadGateway, wantErr: true, wantRequests: 3, wantRetries: 2},
+		{name: "not retryable", option: policy, failures: 1, status: http.StatusNotFound, wantErr: true, wantRequests: 1, wantRetries: 0},
+		{name: "config", config: RetryPolicy{Attempts: 1}, failures: 1, status: http.StatusServiceUnavailable, wantErr: true, wantRequests: 1, wantRetries: 0},
+		{name: "option overrides config", option: policy, config: RetryPolicy{Attempts: 1}, failures: 2, status: http.StatusServiceUnavailable, wantRequests: 3, wantRetries: 2},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &flakyServer{failures: tc.failures, status: tc.status, content: content}
+
+			srv := httptest.NewServer(s)
+			defer srv.Close()
+
+			dir, err := ioutil.TempDir("", "shoal-retry-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			var retries []Event
+
+			app := testApp(t, testRoot(t), Config{Download: Download{Retry: tc.config}},
+				WithDownloadRetry(tc.option),
+				WithEventHandler(func(e Event) {
+					if e.Type == EventRetrying {
+						retries = append(retries, e)
+					}
+				}),
+			)
+
+			f := &gofish.Food{Name: "foo", Version: "1.0.0"}
+			pkg := &gofish.Package{
+				OS:     "linux",
+				Arch:   "amd64",
+				URL:    srv.URL + "/foo",
+				SHA256: fmt.Sprintf("%x", sha256.Sum256(content)),
+			}
+
+			err = app.download(f, pkg, filepath.Join(dir, "foo"))
+
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("want error: %v, got %v", tc.wantErr, err)
+			}
+
+			if s.requests != tc.wantRequests {
+				t.Errorf("want %d requests, got %d", tc.wantRequests, s.requests)
+			}
+
+			if len(retries) != tc.wantRetries {
+				t.Fatalf("want %d Retrying events, got %d", tc.wantRetries, len(retries))
+			}
+
+			for i, e := range retries {
+				if e.Attempt != i+1 || e.URL != pkg.URL || e.Err == nil {
+					t.Errorf("unexpected Retrying event: %+v", e)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	for attempt, max := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if d := p.delay(attempt); d <= max/2 || d > max {
+			t.Errorf("attempt %d: want delay in (%s, %s], got %s", attempt, max/2, max, d)
+		}
+	}
+}
+
+// flakyGit fails the first `failures` calls of every operation accessing remotes with err.
+type flakyGit struct {
+	GitClient
+
+	failures int
+	err      error
+	calls    int
+}
+
+func (g *flakyGit) fail() error {
+	g.calls++
+
+	if g.calls <= g.failures {
+		return g.err
+	}
+
+	return nil
+}
+
+func (g *flakyGit) Clone(repo, dir string) error {
+	return g.fail()
+}
+
+func (g *flakyGit) Fetch(dir, ref string) error {
+	return g.fail()
+}
+
+func (g *flakyGit) ShowOriginHeadBranch(dir string) (string, error) {
+	return "master", g.fail()
+}
+
+func TestGitRetry(t *testing.T) {
+	transient := ErrGit{Command: "git fetch", Output: "fatal: unable to access: Connection reset by peer", Err: errors.New("exit status 128")}
+	permanent := ErrGit{Command: "git fetch", Output: "fatal: repository 'r' not found", Err: errors.New("exit status 128")}
+
+	ops := map[string]func(GitClient) error{
+		"clone": func(g GitClient) error { return g.Clone("r", "dir") },
+		"fetch": func(g GitClient) error { return g.Fetch("dir", "master") },
+		"origin head branch": func(g GitClient) error {
+			_, err := g.ShowOriginHeadBranch("dir")
+			return err
+		},
+	}
+
+	testcases := []struct {
+		name      string
+		failures  int
+		err       error
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "transient", failures: 2, err: transient, wantCalls: 3},
+		{name: "exhausted", failures: 3, err: transient, wantErr: true, wantCalls: 3},
+		{name: "permanent", failures: 1, err: permanent, wantErr: true, wantCalls: 1},
+		{name: "timeout", failures: 1, err: ErrTimeout{Operation: "git fetch", Timeout: time.Second}, wantCalls: 2},
+	}
+
+	for name, op := range ops {
+		for _, tc := range testcases {
+			t.Run(name+"/"+tc.name, func(t *testing.T) {
+				var retries int
+
+				app := testApp(t, testRoot(t), Config{},
+					WithGitRetry(RetryPolicy{Attempts: 3, Backoff: time.Millisecond}),
+					WithEventHandler(func(e Event) {
+						if e.Type == EventRetrying {
+							retries++
+						}
+					}),
+				)
+
+				g := &flakyGit{failures: tc.failures, err: tc.err}
+
+				app.git.(*retryingGit).GitClient = g
+
+				err := op(app.git)
+
+				if tc.wantErr != (err != nil) {
+					t.Fatalf("want error: %v, got %v", tc.wantErr, err)
+				}
+
+				if g.calls != tc.wantCalls || retries != tc.wantCalls-1 {
+					t.Errorf("want %d calls and %d retries, got %d and %d", tc.wantCalls, tc.wantCalls-1, g.calls, retries)
+				}
+			})
+		}
+	}
+}
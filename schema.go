@@ -0,0 +1,116 @@
+package shoal
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// JSONSchemaID is the URL the JSON Schema for shoal.yaml is published at.
+const JSONSchemaID = "https://raw.githubusercontent.com/mumoshu/shoal/master/shoal.schema.json"
+
+// JSONSchema returns the JSON Schema for shoal.yaml, generated from the Config type.
+// Editors like VS Code with the YAML extension can use it for completion and validation.
+func JSONSchema() ([]byte, error) {
+	s := schemaOf(reflect.TypeOf(Config{}))
+
+	s["$schema"] = "http://json-schema.org/draft-07/schema#"
+	s["$id"] = JSONSchemaID
+	s["title"] = "shoal.yaml"
+
+	bs, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(bs, '\n'), nil
+}
+
+// requiredFields lists the keys that must be present in objects of the type, as checked by Config.Validate.
+var requiredFields = map[reflect.Type][]string{
+	reflect.TypeOf(Dependency{}): {"rig", "food"},
+	reflect.TypeOf(Include{}):    {"path"},
+	reflect.TypeOf(Mirror{}):     {"prefix", "url"},
+}
+
+// shorthands lists the types that accept a plain string in place of the object, as implemented by their UnmarshalYAML.
+var shorthands = map[reflect.Type]bool{
+	reflect.TypeOf(Include{}):  true,
+	reflect.TypeOf(Selector{}): true,
+}
+
+func schemaOf(t reflect.Type) map[string]interface{} {
+	if shorthands[t] {
+		return map[string]interface{}{
+			"oneOf": []interface{}{map[string]interface{}{"type": "string"}, schemaOfKind(t)},
+		}
+	}
+
+	return schemaOfKind(t)
+}
+
+func schemaOfKind(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		// Durations are written like `1s` and `500ms`
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Ptr:
+		// Pointers tell an unset field from the zero value, like `false`
+		return schemaOf(t.Elem())
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaOf(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaOf(t.Elem()),
+		}
+	case reflect.Struct:
+		props := map[string]interface{}{}
+
+		s := map[string]interface{}{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+
+			tag := strings.Split(f.Tag.Get("yaml"), ",")
+
+			name := tag[0]
+
+			if len(tag) > 1 && tag[1] == "inline" {
+				// An inline map like Foods.Others accepts any other key
+				s["additionalProperties"] = schemaOf(f.Type.Elem())
+				continue
+			}
+
+			if name == "" || name == "-" {
+				continue
+			}
+
+			props[name] = schemaOf(f.Type)
+		}
+
+		if required, ok := requiredFields[t]; ok {
+			s["required"] = required
+		}
+
+		return s
+	default:
+		return map[string]interface{}{}
+	}
+}
@@ -0,0 +1,22 @@
+package shoal
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestJSONSchemaIsUpToDate(t *testing.T) {
+	want, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("generating json schema: %v", err)
+	}
+
+	got, err := ioutil.ReadFile("shoal.schema.json")
+	if err != nil {
+		t.Fatalf("reading shoal.schema.json: %v", err)
+	}
+
+	if string(got) != string(want) {
+		t.Errorf("shoal.schema.json is outdated: run `make schema` to regenerate it")
+	}
+}
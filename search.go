@@ -0,0 +1,119 @@
+package shoal
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SearchResult is a food found by Search.
+type SearchResult struct {
+	Rig         string `json:"rig"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Homepage    string `json:"homepage,omitempty"`
+	License     string `json:"license,omitempty"`
+	// Version is the version of the food at the head of the rig.
+	Version      string `json:"version"`
+	FoodCommitID string `json:"foodCommitID"`
+}
+
+// Search looks for the foods whose name or description contains the term, case-insensitively,
+// in the head of every rig referenced by the config. An empty term matches all the foods.
+// The rigs are cloned or fetched as they are for Sync. Rotten foods are skipped and reported as RottenFood events.
+func (a *App) Search(config Config, term string) ([]SearchResult, error) {
+	a.setEnv()
+
+	var results []SearchResult
+
+	for _, rig := range config.rigs() {
+		found, err := a.searchRig(rig, term)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, found...)
+	}
+
+	return results, nil
+}
+
+// rigs returns the rigs referenced by the config, each only once, in the order they appear.
+func (config Config) rigs() []string {
+	var (
+		rigs []string
+		seen = map[string]bool{}
+	)
+
+	add := func(rig string) {
+		if rig != "" && !seen[rig] {
+			seen[rig] = true
+			rigs = append(rigs, rig)
+		}
+	}
+
+	add(config.Rig)
+
+	for _, d := range config.dependencies() {
+		add(d.Rig)
+	}
+
+	return rigs
+}
+
+func (a *App) searchRig(rig, term string) ([]SearchResult, error) {
+	workspaceDir, err := a.workspace(rig)
+	if err != nil {
+		return nil, err
+	}
+
+	commitID, err := resolveRef(a.git, workspaceDir, "HEAD")
+	if err != nil {
+		return nil, withRig(err, rig)
+	}
+
+	files, err := filepath.Glob(filepath.Join(workspaceDir, "Food", "*.lua"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+
+	goos, goarch := a.platform()
+
+	term = strings.ToLower(term)
+
+	var results []SearchResult
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".lua")
+
+		script, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := a.evalFood(string(script), goos, goarch, nil)
+		if err != nil {
+			a.reportRottenFoods(name, []ErrRottenFood{{Rig: rig, Food: name, FoodCommitID: commitID, Err: err}})
+			continue
+		}
+
+		if !strings.Contains(strings.ToLower(name), term) && !strings.Contains(strings.ToLower(f.Description), term) {
+			continue
+		}
+
+		results = append(results, SearchResult{
+			Rig:          rig,
+			Name:         name,
+			Description:  f.Description,
+			Homepage:     f.Homepage,
+			License:      f.License,
+			Version:      f.Version,
+			FoodCommitID: commitID,
+		})
+	}
+
+	return results, nil
+}
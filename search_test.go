@@ -0,0 +1,51 @@
+package shoal
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	for _, provider := range []string{"native", "go-git"} {
+		t.Run(provider, func(t *testing.T) {
+			testSearch(t, provider)
+		})
+	}
+}
+
+func testSearch(t *testing.T, provider string) {
+	rig := testRig(t, fooRevision("1.0.0"))
+	defer os.RemoveAll(rig)
+
+	commitFile(t, rig, "Food/bar.lua", `food = { name = "bar", description = "Bar Tool", license = "MIT", version = "0.1.0" }`, "add bar")
+
+	root := testRoot(t)
+
+	config := Config{Rig: rig, Git: Git{Provider: provider}}
+
+	search := func(term string) []SearchResult {
+		t.Helper()
+
+		results, err := testApp(t, root, config).Search(config, term)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return results
+	}
+
+	if results := search("tool"); len(results) != 1 || results[0].Name != "bar" || results[0].License != "MIT" || results[0].Rig != rig {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	if results := search(""); len(results) != 2 {
+		t.Fatalf("want all the foods, got %+v", results)
+	}
+
+	// The workspace is fetched, so that a food added to the rig afterwards is found
+	commitFile(t, rig, "Food/baz.lua", `food = { name = "baz", version = "1.0.0" }`, "add baz")
+
+	if results := search("baz"); len(results) != 1 || results[0].Version != "1.0.0" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
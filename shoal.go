@@ -6,20 +6,18 @@ import (
 	"github.com/Masterminds/semver"
 	"github.com/fishworks/gofish"
 	"github.com/fishworks/gofish/pkg/home"
-	"github.com/fishworks/gofish/pkg/ohai"
 	"github.com/fishworks/gofish/pkg/rig/installer"
-	"github.com/yuin/gluamapper"
-	"github.com/yuin/gopher-lua"
 	"golang.org/x/xerrors"
 	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"reflect"
 	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -37,6 +35,37 @@ func LogOutput(w io.Writer) Option {
 	}
 }
 
+// Force makes shoal reinstall foods even when the selected version is already installed.
+func Force(force bool) Option {
+	return func(app *App) {
+		app.force = force
+	}
+}
+
+// KeepGoing makes Sync and Plan continue past failed dependencies, and return an ErrDependencies
+// listing all of them.
+func KeepGoing(keepGoing bool) Option {
+	return func(app *App) {
+		app.keepGoing = keepGoing
+	}
+}
+
+// StrictFoods makes shoal fail when the selected revision of a food definition or any newer one is rotten,
+// instead of skipping the rotten revisions.
+func StrictFoods(strict bool) Option {
+	return func(app *App) {
+		app.strictFoods = strict
+	}
+}
+
+// WithRootDir makes shoal install foods into the directory instead of `.shoal` in the working directory.
+// A relative path is relative to the working directory.
+func WithRootDir(dir string) Option {
+	return func(app *App) {
+		app.RootDir = dir
+	}
+}
+
 func New(opts ...Option) (*App, error) {
 	wd, err := os.Getwd()
 	if err != nil {
@@ -54,6 +83,18 @@ func New(opts ...Option) (*App, error) {
 		o(app)
 	}
 
+	if !filepath.IsAbs(app.RootDir) {
+		app.RootDir = filepath.Join(wd, app.RootDir)
+	}
+
+	if err := app.configure(Config{}); err != nil {
+		return nil, err
+	}
+
+	if app.foodTimeout == 0 {
+		app.foodTimeout = defaultFoodTimeout
+	}
+
 	if app.logOutput == nil {
 		app.logOutput = os.Stderr
 	}
@@ -71,14 +112,107 @@ type App struct {
 	fetchedMutex sync.Mutex
 	fetched      map[string]bool
 
+	stateMutex      sync.Mutex
+	generationMutex sync.Mutex
+
+	force       bool
+	keepGoing   bool
+	strictFoods bool
+
+	// os and arch are the target platform. Empty for the platform shoal is running on.
+	os   string
+	arch string
+
+	// overrides are the settings given as options
+	overrides overrides
+
+	mirrors []Mirror
+
+	httpClient *http.Client
+
+	gitRetry      RetryPolicy
+	downloadRetry RetryPolicy
+
+	gitTimeout      time.Duration
+	downloadTimeout time.Duration
+	foodTimeout     time.Duration
+
 	logOutput io.Writer
 	logger    *log.Logger
+
+	eventHandler func(Event)
+}
+
+// overrides are the settings given as options, which take precedence over the ones in the config.
+type overrides struct {
+	mirrors []Mirror
+	http    HTTP
+
+	gitRetry      RetryPolicy
+	downloadRetry RetryPolicy
+
+	gitTimeout      time.Duration
+	downloadTimeout time.Duration
+}
+
+// configure applies the settings in the config, overridden by the ones given as options.
+func (a *App) configure(config Config) error {
+	o := a.overrides
+
+	// The longest matching prefix wins, and the first one among the mirrors of the same prefix
+	a.mirrors = append(append([]Mirror{}, o.mirrors...), config.Mirrors...)
+
+	h := config.HTTP
+
+	if o.http.Proxy != "" {
+		h.Proxy = o.http.Proxy
+	}
+
+	if o.http.CABundle != "" {
+		h.CABundle = o.http.CABundle
+	}
+
+	if o.http.InsecureSkipVerify != nil {
+		h.InsecureSkipVerify = o.http.InsecureSkipVerify
+	}
+
+	c, err := newHTTPClient(h)
+	if err != nil {
+		return err
+	}
+
+	a.httpClient = c
+
+	a.gitRetry = config.Git.Retry
+	a.gitRetry.merge(o.gitRetry)
+	a.gitRetry = a.gitRetry.withDefaults(isRetryableGitError)
+
+	a.downloadRetry = config.Download.Retry
+	a.downloadRetry.merge(o.downloadRetry)
+	a.downloadRetry = a.downloadRetry.withDefaults(isRetryableDownloadError)
+
+	a.gitTimeout = config.Git.Timeout
+	if o.gitTimeout != 0 {
+		a.gitTimeout = o.gitTimeout
+	}
+
+	a.downloadTimeout = config.Download.Timeout
+	if o.downloadTimeout != 0 {
+		a.downloadTimeout = o.downloadTimeout
+	}
+
+	return nil
 }
 
 type versionedFood struct {
 	foodCommitID string
 	description  string
+	date         time.Time
 	food         gofish.Food
+	// script is the Lua food definition the food is read from.
+	script string
+	// newerRotten is the number of rotten revisions of the food definition newer than this one.
+	newerRotten int
 }
 
 func (a *App) setEnv() {
@@ -88,6 +222,9 @@ func (a *App) setEnv() {
 	os.Setenv("GOFISH_BINPATH", filepath.Join(GofishRoot, "bin"))
 }
 
+// Init creates the directories shoal installs foods into.
+// The bin dir is created as a symlink to the bin dir of the first generation, and a bin dir created by
+// an older version of shoal is moved into the first generation.
 func (a *App) Init() error {
 	a.setEnv()
 
@@ -95,7 +232,6 @@ func (a *App) Init() error {
 		home.String(),
 		home.Barrel(),
 		home.Rigs(),
-		home.BinPath(),
 		home.Cache(),
 	}
 
@@ -115,207 +251,186 @@ func (a *App) Init() error {
 			if err := os.MkdirAll(d, 0755); err != nil {
 				return err
 			}
+
+			a.emit(Event{Type: EventDirectoryCreated, Dir: d})
 		}
 	}
 
+	a.generationMutex.Lock()
+	defer a.generationMutex.Unlock()
+
+	if _, err := a.currentGeneration(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// Ensure installs the newest version of the food that satisfies the semver constraint.
 func (a *App) Ensure(rig, food, constraint string) error {
 	a.setEnv()
 
-	g := a.git
-
-	var constraints *semver.Constraints
-
-	if constraint != "" {
-		var err error
+	return a.withGeneration(func(gen *generation) error {
+		return a.ensure(gen, rig, food, constraint, nil)
+	})
+}
 
-		constraints, err = semver.NewConstraint(constraint)
-		if err != nil {
-			return fmt.Errorf("parsing semver constraint from %q: %w", constraint, err)
-		}
+// ensure installs the food into the generation. vars are exposed to the food definition as `shoal.vars`.
+func (a *App) ensure(gen *generation, rig, food, constraint string, vars map[string]string) error {
+	if err := a.doEnsure(gen, rig, food, constraint, vars); err != nil {
+		a.emit(Event{
+			Type:       EventFailed,
+			Rig:        rig,
+			Food:       food,
+			Constraint: constraint,
+			Err:        err,
+		})
+
+		return err
 	}
 
-	GofishRoot := a.RootDir
-
-	var versions []versionedFood
-
-	listVersions := true
-	if listVersions {
-		a.logger.Println("Listing versions")
-
-		h := sha1.New()
-		h.Write([]byte(rig))
-		hash := fmt.Sprintf("%x", h.Sum(nil))
-
-		workspaceCacheKey := rig
-		workspaceCacheKey = strings.TrimPrefix(workspaceCacheKey, "https://")
-		workspaceCacheKey = strings.TrimPrefix(workspaceCacheKey, "http://")
-		workspaceCacheKey = strings.TrimPrefix(workspaceCacheKey, "git@")
-		workspaceCacheKey = strings.ReplaceAll(workspaceCacheKey, string(os.PathSeparator), "-")
-		workspaceCacheKey += "-" + hash
+	return nil
+}
 
-		workspaceCacheDir := filepath.Join(GofishRoot, "workspaces", workspaceCacheKey)
+func (a *App) doEnsure(gen *generation, rig, food, constraint string, vars map[string]string) error {
+	version, err := a.resolve(rig, food, constraint, vars)
+	if err != nil {
+		return err
+	}
 
-		if _, err := os.Lstat(workspaceCacheDir); os.IsNotExist(err) {
-			if err := os.MkdirAll(workspaceCacheDir, 0755); err != nil {
-				return fmt.Errorf("creating workspaces cache dir: %w", err)
-			}
-		}
+	pkg, err := a.getPackage(&version.food)
+	if err != nil {
+		return ErrInstall{Food: version.food.Name, Version: version.food.Version, Err: err}
+	}
 
-		a.logger.Printf("Reading workspace cache dir at %s", workspaceCacheDir)
+	return a.installVersion(gen, rig, food, constraint, version, pkg)
+}
 
-		fileInfoList, err := ioutil.ReadDir(workspaceCacheDir)
+// installVersion installs the package of the resolved food version into the generation,
+// unless it is already installed.
+func (a *App) installVersion(gen *generation, rig, food, constraint string, version *versionedFood, pkg *gofish.Package) error {
+	if !a.force {
+		installedFoods, err := a.installedFoods(gen)
 		if err != nil {
 			return err
 		}
 
-		var workspaceDir string
-
-		for _, info := range fileInfoList {
-			if !info.IsDir() {
-				continue
-			}
-
-			d := filepath.Join(workspaceCacheDir, info.Name())
-
-			rigIDFile := filepath.Join(d, "RIG")
-
-			a.logger.Printf("reading rig ID file at %s", rigIDFile)
-
-			bs, err := ioutil.ReadFile(rigIDFile)
-			if err != nil {
-				if os.IsNotExist(err) {
-					return fmt.Errorf("broken shoal cache: missing RIG file: please remove %s and try again", d)
-				}
-				return fmt.Errorf("reading RIG file: %w", err)
-			}
-
-			rigID := string(bs)
+		if installed, ok := installedFoods[version.food.Name]; ok && installed.upToDate(&version.food, pkg, gen.binDir()) {
+			a.logger.Printf("%s %s is already installed. skipping.", version.food.Name, version.food.Version)
+
+			a.emit(Event{
+				Type:         EventSkipped,
+				Rig:          rig,
+				Food:         food,
+				Constraint:   constraint,
+				Version:      version.food.Version,
+				FoodCommitID: version.foodCommitID,
+				Reason:       "already installed",
+			})
 
-			if rigID == rig {
-				workspaceDir = d
-				break
-			}
+			return nil
 		}
+	}
 
-		if workspaceDir != "" {
-			a.logger.Printf("locking workspace dir at %s", workspaceDir)
-			a.fetchedMutex.Lock()
-			defer func() {
-				a.logger.Printf("unlocking workspace dir at %s", workspaceDir)
-				a.fetchedMutex.Unlock()
-			}()
+	a.logger.Printf("installing %s %s...", version.food.Name, version.food.Version)
 
-			if fetched := a.fetched[workspaceDir]; !fetched {
-				a.logger.Printf("getting origin head branch in %s", workspaceDir)
+	if err := a.install(&version.food, pkg, gen.binDir()); err != nil {
+		return ErrInstall{Food: version.food.Name, Version: version.food.Version, Err: err}
+	}
 
-				b, err := g.ShowOriginHeadBranch(workspaceDir)
-				if err != nil {
-					return err
-				}
+	installed, err := a.newInstalledFood(rig, version, pkg)
+	if err != nil {
+		return err
+	}
 
-				a.logger.Printf("fetching remote changes in %s", workspaceDir)
+	if err := a.recordInstalledFood(gen, installed); err != nil {
+		return err
+	}
 
-				if err := g.Fetch(workspaceDir, b); err != nil {
-					return err
-				}
+	gen.changed = true
 
-				a.logger.Printf("force-checking-out remote changes in %s", workspaceDir)
+	a.emit(Event{
+		Type:         EventInstalled,
+		Rig:          rig,
+		Food:         food,
+		Constraint:   constraint,
+		Version:      version.food.Version,
+		FoodCommitID: version.foodCommitID,
+		Caveats:      version.food.Caveats,
+	})
 
-				if err := g.ForceCheckout(workspaceDir, b); err != nil {
-					return err
-				}
+	a.logger.Printf("installed %s %s.", version.food.Name, version.food.Version)
 
-				a.logger.Printf("writing rig ID file in %s", workspaceDir)
+	installDefaultFishFood := false
+	if installDefaultFishFood {
+		a.logger.Println("Installing default fish food...")
 
-				// Force check-out using go-git seems to remove all the uncommitted changes to the worktree so
-				// the RIG file.
-				// We have to recreate it otherwise shoal is unable to detect if this workspace dir is that of this rig
-				if err := ioutil.WriteFile(filepath.Join(workspaceDir, "RIG"), []byte(rig), 0644); err != nil {
-					return fmt.Errorf("writing RIG file: %w", err)
-				}
+		i, err := installer.New(rig, "", "")
+		if err != nil {
+			return err
+		}
 
-				a.fetched[workspaceDir] = true
-			}
-		} else {
-			workspaceDir = filepath.Join(workspaceCacheDir, fmt.Sprintf("%d", len(fileInfoList)))
+		start := time.Now()
+		if err := installer.Install(i); err != nil {
+			return err
+		}
 
-			a.logger.Printf("cloning rig %q into %q", rig, workspaceDir)
+		t := time.Now()
 
-			if err := g.Clone(rig, workspaceDir); err != nil {
-				return err
-			}
+		a.logger.Printf("rig constructed in %s", t.Sub(start).String())
+	}
 
-			a.logger.Printf("creating RIG ID file in %s", workspaceDir)
+	return nil
+}
 
-			if err := ioutil.WriteFile(filepath.Join(workspaceDir, "RIG"), []byte(rig), 0644); err != nil {
-				return fmt.Errorf("writing RIG file: %w", err)
-			}
-		}
+// resolve finds the newest version of the food in the rig that satisfies the semver constraint.
+// An empty constraint selects the food from the latest commit.
+func (a *App) resolve(rig, food, constraint string, vars map[string]string) (*versionedFood, error) {
+	a.emit(Event{
+		Type:       EventResolveStarted,
+		Rig:        rig,
+		Food:       food,
+		Constraint: constraint,
+	})
 
-		filePath := filepath.Join("Food", fmt.Sprintf("%s.lua", food))
+	var constraints *semver.Constraints
 
-		a.logger.Printf("running git-log in %s for path %s", workspaceDir, filePath)
+	if constraint != "" {
+		var err error
 
-		gitLogOutput, err := g.Log(workspaceDir, filePath)
+		constraints, err = semver.NewConstraint(constraint)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("parsing semver constraint from %q: %w", constraint, err)
 		}
+	}
 
-		for _, l := range strings.Split(gitLogOutput, "\n") {
-			items := strings.SplitN(l, " ", 2)
-
-			if len(items) != 2 {
-				continue
-			}
-
-			commitID := items[0]
-			description := items[1]
-
-			luaScript, err := g.Show(workspaceDir, commitID, filePath)
-			if err != nil {
-				return err
-			}
+	versions, rotten, err := a.listVersions(rig, food, vars)
+	if err != nil {
+		return nil, err
+	}
 
-			var food gofish.Food
+	a.reportRottenFoods(food, rotten)
 
-			if ok, err := func() (bool, error) {
-				l := lua.NewState()
-				defer l.Close()
-				if err := l.DoString(luaScript); err != nil {
-					if strings.Contains(err.Error(), "syntax error") {
-						return false, nil
-					}
-					return false, fmt.Errorf("executing lua: %w\n\nSCRIPT:\n%s", err, luaScript)
-				}
+	listed := Event{
+		Type:       EventVersionsListed,
+		Rig:        rig,
+		Food:       food,
+		Constraint: constraint,
+	}
 
-				if err := gluamapper.Map(l.GetGlobal(strings.ToLower(reflect.TypeOf(food).Name())).(*lua.LTable), &food); err != nil {
-					return false, fmt.Errorf("reading lua execution result: %w", err)
-				}
+	for _, v := range versions {
+		listed.Versions = append(listed.Versions, v.food.Version)
+	}
 
-				return true, nil
-			}(); err != nil {
-				return err
-			} else if !ok {
-				ohai.Ohaif("Ignored rotten fish %q from commit %q", food, commitID)
-				continue
-			}
+	a.emit(listed)
 
-			versions = append(versions, versionedFood{
-				foodCommitID: commitID,
-				description:  description,
-				food:         food,
-			})
+	if len(versions) == 0 {
+		// The food exists, but none of its revisions could be evaluated
+		if len(rotten) > 0 {
+			return nil, ErrRottenFoods(rotten)
 		}
 
-		if len(versions) > 0 {
-			a.logger.Printf("Fetched %d versions for food %q", len(versions), versions[0].food.Name)
-			for i, v := range versions {
-				a.logger.Printf("%3d: %s %s", i, v.foodCommitID[:8], v.food.Version)
-			}
-		}
+		return nil, ErrNoMatchingVersion{Rig: rig, Food: food, Constraint: constraint}
 	}
 
 	var version versionedFood
@@ -336,7 +451,7 @@ func (a *App) Ensure(rig, food, constraint string) error {
 		for k := range verToFood {
 			v, err := semver.NewVersion(k)
 			if err != nil {
-				return fmt.Errorf("parsing %q as semver: %w", k, err)
+				return nil, fmt.Errorf("parsing %q as semver: %w", k, err)
 			}
 
 			vers = append(vers, v)
@@ -354,111 +469,390 @@ func (a *App) Ensure(rig, food, constraint string) error {
 		}
 
 		if !found {
-			return fmt.Errorf(
-				"finding food: no food matching the semver constraint %q found out of %d food versions",
-				constraint,
-				len(versions),
-			)
+			// Any of the rotten revisions might have been the one satisfying the constraint
+			if a.strictFoods && len(rotten) > 0 {
+				return nil, ErrRottenFoods(rotten)
+			}
+
+			return nil, ErrNoMatchingVersion{Rig: rig, Food: food, Constraint: constraint, Versions: listed.Versions}
 		}
 	}
 
-	a.logger.Printf("installing %s %s...", version.food.Name, version.food.Version)
+	if a.strictFoods && version.newerRotten > 0 {
+		return nil, ErrRottenFoods(rotten[:version.newerRotten])
+	}
+
+	a.emit(Event{
+		Type:         EventVersionSelected,
+		Rig:          rig,
+		Food:         food,
+		Constraint:   constraint,
+		Version:      version.food.Version,
+		FoodCommitID: version.foodCommitID,
+	})
 
-	if err := version.food.Install(); err != nil {
-		return fmt.Errorf("installing %s %s: %w", version.food.Name, version.food.Version, err)
+	return &version, nil
+}
+
+// reportRottenFoods logs the rotten revisions of the food and emits a RottenFood event for each.
+func (a *App) reportRottenFoods(food string, rotten []ErrRottenFood) {
+	if len(rotten) == 0 {
+		return
 	}
 
-	a.logger.Printf("installed %s %s.", version.food.Name, version.food.Version)
+	a.logger.Printf("Ignored %d rotten revisions of food %q:", len(rotten), food)
 
-	installDefaultFishFood := false
-	if installDefaultFishFood {
-		ohai.Ohailn("Installing default fish food...")
+	for _, r := range rotten {
+		a.logger.Printf("  %s: %v", shortCommitID(r.FoodCommitID), r.Err)
 
-		i, err := installer.New(rig, "", "")
+		a.emit(Event{
+			Type:         EventRottenFood,
+			Rig:          r.Rig,
+			Food:         r.Food,
+			FoodCommitID: r.FoodCommitID,
+			Err:          r.Err,
+		})
+	}
+}
+
+// rigKey returns the name of a directory that is unique to the rig, like `github.com-org-rig-<sha1 of the rig>`.
+func rigKey(rig string) string {
+	h := sha1.New()
+	h.Write([]byte(rig))
+	hash := fmt.Sprintf("%x", h.Sum(nil))
+
+	key := rig
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	key = strings.TrimPrefix(key, "git@")
+	key = strings.ReplaceAll(key, string(os.PathSeparator), "-")
+	key += "-" + hash
+
+	return key
+}
+
+// workspace returns the directory the rig is cloned into, cloning it or fetching its remote changes when needed.
+// The remote changes are fetched only once per App.
+func (a *App) workspace(rig string) (string, error) {
+	g := a.git
+
+	// The workspace is identified by the URL it is cloned from, so that adding or changing a mirror
+	// doesn't keep fetching from the previous remote
+	if u := a.rewriteURL(rig); u != rig {
+		a.logger.Printf("using mirror %s for rig %s", u, rig)
+
+		rig = u
+	}
+
+	GofishRoot := a.RootDir
+
+	workspaceCacheDir := filepath.Join(GofishRoot, "workspaces", rigKey(rig))
+
+	if _, err := os.Lstat(workspaceCacheDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(workspaceCacheDir, 0755); err != nil {
+			return "", fmt.Errorf("creating workspaces cache dir: %w", err)
+		}
+	}
+
+	a.logger.Printf("Reading workspace cache dir at %s", workspaceCacheDir)
+
+	fileInfoList, err := ioutil.ReadDir(workspaceCacheDir)
+	if err != nil {
+		return "", err
+	}
+
+	var workspaceDir string
+
+	for _, info := range fileInfoList {
+		if !info.IsDir() {
+			continue
+		}
+
+		d := filepath.Join(workspaceCacheDir, info.Name())
+
+		rigIDFile := filepath.Join(d, "RIG")
+
+		a.logger.Printf("reading rig ID file at %s", rigIDFile)
+
+		bs, err := ioutil.ReadFile(rigIDFile)
 		if err != nil {
-			return err
+			if os.IsNotExist(err) {
+				return "", ErrBrokenCache{Path: d, Reason: "missing RIG file"}
+			}
+			return "", fmt.Errorf("reading RIG file: %w", err)
 		}
 
-		start := time.Now()
-		if err := installer.Install(i); err != nil {
-			return err
+		rigID := string(bs)
+
+		if rigID == rig {
+			workspaceDir = d
+			break
 		}
+	}
 
-		t := time.Now()
+	if workspaceDir != "" {
+		a.logger.Printf("locking workspace dir at %s", workspaceDir)
+		a.fetchedMutex.Lock()
+		defer func() {
+			a.logger.Printf("unlocking workspace dir at %s", workspaceDir)
+			a.fetchedMutex.Unlock()
+		}()
+
+		if fetched := a.fetched[workspaceDir]; !fetched {
+			a.logger.Printf("getting origin head branch in %s", workspaceDir)
+
+			b, err := g.ShowOriginHeadBranch(workspaceDir)
+			if err != nil {
+				return "", withRig(err, rig)
+			}
+
+			a.logger.Printf("fetching remote changes in %s", workspaceDir)
+
+			if err := g.Fetch(workspaceDir, b); err != nil {
+				return "", withRig(err, rig)
+			}
+
+			a.logger.Printf("force-checking-out remote changes in %s", workspaceDir)
+
+			if err := g.ForceCheckout(workspaceDir, b); err != nil {
+				return "", withRig(err, rig)
+			}
+
+			a.logger.Printf("writing rig ID file in %s", workspaceDir)
 
-		ohai.Successf("rig constructed in %s\n", t.Sub(start).String())
+			// Force check-out using go-git seems to remove all the uncommitted changes to the worktree so
+			// the RIG file.
+			// We have to recreate it otherwise shoal is unable to detect if this workspace dir is that of this rig
+			if err := ioutil.WriteFile(filepath.Join(workspaceDir, "RIG"), []byte(rig), 0644); err != nil {
+				return "", fmt.Errorf("writing RIG file: %w", err)
+			}
+
+			a.fetched[workspaceDir] = true
+		}
+	} else {
+		workspaceDir = filepath.Join(workspaceCacheDir, fmt.Sprintf("%d", len(fileInfoList)))
+
+		a.logger.Printf("cloning rig %q into %q", rig, workspaceDir)
+
+		if err := g.Clone(rig, workspaceDir); err != nil {
+			return "", withRig(err, rig)
+		}
+
+		a.logger.Printf("creating RIG ID file in %s", workspaceDir)
+
+		if err := ioutil.WriteFile(filepath.Join(workspaceDir, "RIG"), []byte(rig), 0644); err != nil {
+			return "", fmt.Errorf("writing RIG file: %w", err)
+		}
 	}
 
-	return nil
+	return workspaceDir, nil
 }
 
+// listVersions returns every revision of the food found in the history of the rig, newest first.
+// Each revision is evaluated for the target platform with the vars.
+// The revisions that fail to evaluate are returned separately as rotten, newest first.
+func (a *App) listVersions(rig, food string, vars map[string]string) ([]versionedFood, []ErrRottenFood, error) {
+	g := a.git
+
+	var (
+		versions []versionedFood
+		rotten   []ErrRottenFood
+	)
+
+	a.logger.Println("Listing versions")
+
+	workspaceDir, err := a.workspace(rig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filePath := filepath.Join("Food", fmt.Sprintf("%s.lua", food))
+
+	a.logger.Printf("running git-log in %s for path %s", workspaceDir, filePath)
+
+	gitLogOutput, err := logWithDates(g, workspaceDir, filePath)
+	if err != nil {
+		return nil, nil, withRig(err, rig)
+	}
+
+	goos, goarch := a.platform()
+
+	for _, l := range strings.Split(gitLogOutput, "\n") {
+		items := strings.SplitN(l, " ", 3)
+
+		if len(items) != 3 {
+			continue
+		}
+
+		commitID := items[0]
+		description := items[2]
+
+		unix, err := strconv.ParseInt(items[1], 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing commit time of %s: %w", commitID, err)
+		}
+
+		luaScript, err := g.Show(workspaceDir, commitID, filePath)
+		if err != nil {
+			return nil, nil, withRig(err, rig)
+		}
+
+		f, err := a.evalFood(luaScript, goos, goarch, vars)
+		if err != nil {
+			rotten = append(rotten, ErrRottenFood{Rig: rig, Food: food, FoodCommitID: commitID, Err: err})
+			continue
+		}
+
+		var date time.Time
+
+		// The time is 0 when the GitClient can't log dates
+		if unix > 0 {
+			date = time.Unix(unix, 0)
+		}
+
+		versions = append(versions, versionedFood{
+			foodCommitID: commitID,
+			description:  description,
+			date:         date,
+			food:         f,
+			script:       luaScript,
+			newerRotten:  len(rotten),
+		})
+	}
+
+	if len(versions) > 0 {
+		a.logger.Printf("Fetched %d versions for food %q", len(versions), versions[0].food.Name)
+		for i, v := range versions {
+			a.logger.Printf("%3d: %s %s", i, shortCommitID(v.foodCommitID), v.food.Version)
+		}
+	}
+
+	return versions, rotten, nil
+}
+
+// shortCommitID abbreviates the commit ID for logging.
+// It accepts IDs that are already abbreviated, like the ones printed by `git log --oneline`.
+func shortCommitID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// BinPath returns the path to the bin dir containing the links to the installed foods.
+// It is a symlink to the bin dir of the current generation, created by Init.
 func (a *App) BinPath() string {
 	return home.BinPath()
 }
 
+// InitGitProvider initializes the git client for the provider in the config, and applies the settings in the config
+// like mirrors, HTTP settings, retry policies and timeouts. The settings given as options take precedence over the ones in the config.
 func (a *App) InitGitProvider(config Config) error {
-	var g GitClient
-	switch p := config.Git.Provider; p {
-	case "go-git":
-		g = &GoGit{}
-	case "":
-		g = &NativeGit{}
-	default:
-		return fmt.Errorf("invalid git.provider: %s", p)
+	if err := a.configure(config); err != nil {
+		return err
 	}
 
-	a.git = g
+	g, err := newGitClient(config.Git.Provider, a.gitTimeout)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := g.(*GoGit); ok {
+		installGoGitHTTPClient(a.httpClient)
+	}
+
+	a.git = &retryingGit{GitClient: g, app: a, policy: a.gitRetry}
 
 	return nil
 }
 
-func (a *App) Sync(config Config) (finalErr error) {
-	defer func() {
-		if err := recover(); err != nil {
-			finalErr = xerrors.Errorf("sync failed due to panic: %w\nSTACK TRACE:\n%s", err, debug.Stack())
-		}
-	}()
+func newGitClient(provider string, timeout time.Duration) (GitClient, error) {
+	switch provider {
+	case "go-git":
+		return &GoGit{Timeout: timeout}, nil
+	case "", "native":
+		return &NativeGit{Timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("invalid git.provider: %s", provider)
+	}
+}
 
+// dependencies returns all the dependencies declared in the config, including the ones declared under `foods`.
+// Each pair of rig and food appears only once.
+func (config Config) dependencies() []Dependency {
 	rig := config.Rig
 
+	var deps []Dependency
+
 	if v := config.Foods.Helm; v != "" {
-		if err := a.Ensure(rig, "helm", v); err != nil {
-			return err
-		}
+		deps = append(deps, Dependency{Rig: rig, Food: "helm", Version: v})
 	}
 
 	if v := config.Foods.Kubectl; v != "" {
-		if err := a.Ensure(rig, "kubectl", v); err != nil {
-			return err
-		}
+		deps = append(deps, Dependency{Rig: rig, Food: "kubectl", Version: v})
 	}
 
 	if v := config.Foods.Helmfile; v != "" {
-		if err := a.Ensure(rig, "helmfile", v); err != nil {
-			return err
-		}
+		deps = append(deps, Dependency{Rig: rig, Food: "helmfile", Version: v})
 	}
 
 	if v := config.Foods.Eksctl; v != "" {
-		if err := a.Ensure(rig, "eksctl", v); err != nil {
-			return err
-		}
+		deps = append(deps, Dependency{Rig: rig, Food: "eksctl", Version: v})
 	}
 
 	for food, version := range config.Foods.Others {
-		if err := a.Ensure(rig, food, version); err != nil {
-			return err
-		}
+		deps = append(deps, Dependency{Rig: rig, Food: food, Version: version})
 	}
 
-	for _, d := range config.Dependencies {
-		if err := a.Ensure(d.Rig, d.Food, d.Version); err != nil {
-			return err
+	// A dependency overrides the food of the same rig declared under `foods`
+	return mergeDependencies(deps, config.Dependencies)
+}
+
+func (a *App) Sync(config Config) (finalErr error) {
+	defer func() {
+		if err := recover(); err != nil {
+			finalErr = xerrors.Errorf("sync failed due to panic: %w\nSTACK TRACE:\n%s", err, debug.Stack())
 		}
+	}()
+
+	a.setEnv()
+
+	return a.withGeneration(func(gen *generation) error {
+		return a.sync(gen, config)
+	})
+}
+
+func (a *App) sync(gen *generation, config Config) error {
+	var errs ErrDependencies
+
+	goos, goarch := a.platform()
+
+	for _, d := range config.dependencies() {
+		d, ok := d.forPlatform(goos, goarch)
+		if !ok {
+			a.skipDependency(d, goos, goarch)
+			continue
+		}
+
+		if err := a.ensure(gen, d.Rig, d.Food, d.Version, d.Vars); err != nil {
+			if !a.keepGoing {
+				return err
+			}
+
+			errs = append(errs, ErrDependency{Rig: d.Rig, Food: d.Food, Constraint: d.Version, Err: err})
+		}
+	}
+
+	// Nothing is made current when any dependency failed, even in the keep-going mode
+	if len(errs) > 0 {
+		return errs
 	}
 
-	if v := config.Helm.Plugins.Diff; v != "" {
-		pluginInstall := exec.Command(filepath.Join(a.BinPath(), "helm"), "plugin", "install", "https://github.com/databus23/helm-diff", "--version", v)
+	if config.Helm.Plugins.Diff != "" && !a.isHost() {
+		// helm built for another platform can't be run to install the plugin
+		a.skipHelmDiff(config.Helm.Plugins.Diff, "helm plugins can't be installed for a platform other than the host")
+	} else if v := config.Helm.Plugins.Diff; v != "" {
+		pluginInstall := exec.Command(filepath.Join(gen.binDir(), "helm"), "plugin", "install", "https://github.com/databus23/helm-diff", "--version", v)
 
 		var homeSet bool
 
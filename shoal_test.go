@@ -0,0 +1,462 @@
+package shoal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testRig creates a git repository with a commit for each revision of Food/foo.lua, oldest first.
+func testRig(t *testing.T, revisions ...string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir, err := ioutil.TempDir("", "shoal-rig-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testGit(t, dir, "init", "-q")
+
+	for i, r := range revisions {
+		commitFile(t, dir, "Food/foo.lua", r, fmt.Sprintf("revision %d", i))
+	}
+
+	return dir
+}
+
+// commitFile writes the file in the git repository and commits it.
+func commitFile(t *testing.T, dir, path, content, message string) {
+	t.Helper()
+
+	p := filepath.Join(dir, filepath.FromSlash(path))
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testGit(t, dir, "add", path)
+	testGit(t, dir, "commit", "-q", "-m", message)
+}
+
+func testGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// testRoot creates a temporary root directory to install foods into, which is removed when the test finishes.
+// HOME is pointed to a directory in it until then, so that the packages downloaded into the cache in
+// the home directory aren't shared across tests.
+func testRoot(t *testing.T) string {
+	t.Helper()
+
+	root, err := ioutil.TempDir("", "shoal-root-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home, ok := os.LookupEnv("HOME")
+
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("HOME", home)
+		} else {
+			os.Unsetenv("HOME")
+		}
+
+		os.RemoveAll(root)
+	})
+
+	if err := os.Setenv("HOME", filepath.Join(root, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	return root
+}
+
+// testApp creates an app installing into root, initialized with the git provider of the config.
+func testApp(t *testing.T, root string, config Config, opts ...Option) *App {
+	t.Helper()
+
+	app, err := New(append([]Option{LogOutput(ioutil.Discard), WithRootDir(root)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.InitGitProvider(config); err != nil {
+		t.Fatal(err)
+	}
+
+	return app
+}
+
+func fooRevision(version string) string {
+	return fmt.Sprintf(`food = { name = "foo", version = %q }`, version)
+}
+
+// testPackages is an HTTP server serving the packages of the foods defined by food.
+type testPackages struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	archives map[string][]byte
+}
+
+func newTestPackages(t *testing.T) *testPackages {
+	t.Helper()
+
+	p := &testPackages{archives: map[string][]byte{}}
+
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		archive, ok := p.archives[r.URL.Path]
+		p.mu.Unlock()
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write(archive)
+	}))
+
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+// food returns the definition of the food with a package for each of the platforms, like `linux/amd64`.
+// Each package is a tar.gz archive containing the executable bin/<name>, whose content is packageContent.
+func (p *testPackages) food(t *testing.T, name, version string, platforms ...string) string {
+	t.Helper()
+
+	var packages []string
+
+	for _, platform := range platforms {
+		osArch := strings.SplitN(platform, "/", 2)
+
+		archive := tarGz(t, name, packageContent(name, version, platform))
+
+		path := fmt.Sprintf("/%s-%s-%s-%s.tar.gz", name, version, osArch[0], osArch[1])
+
+		p.mu.Lock()
+		p.archives[path] = archive
+		p.mu.Unlock()
+
+		packages = append(packages, fmt.Sprintf(
+			`{ os = %q, arch = %q, url = %q, sha256 = "%x", resources = { { path = %q, installpath = "bin/%s", executable = true } } },`,
+			osArch[0], osArch[1], p.URL+path, sha256.Sum256(archive), name, name,
+		))
+	}
+
+	return fmt.Sprintf("food = {\n  name = %q,\n  version = %q,\n  packages = {\n    %s\n  },\n}", name, version, strings.Join(packages, "\n    "))
+}
+
+// packageContent returns the content of the executable in the package of the food for the platform.
+func packageContent(name, version, platform string) string {
+	return fmt.Sprintf("#!/bin/sh\necho %s %s %s\n", name, version, platform)
+}
+
+func tarGz(t *testing.T, name, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// readLink returns the content of the file the link in binDir points to.
+func readLink(t *testing.T, binDir, name string) string {
+	t.Helper()
+
+	bs, err := ioutil.ReadFile(filepath.Join(binDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(bs)
+}
+
+func TestResolveRottenFoods(t *testing.T) {
+	rig := testRig(t,
+		fooRevision("1.0.0"),
+		`food = {`,
+		fooRevision("1.1.0"),
+		`error("broken")`,
+	)
+	defer os.RemoveAll(rig)
+
+	testcases := []struct {
+		name        string
+		constraint  string
+		strict      bool
+		wantVersion string
+		wantRotten  int
+	}{
+		{name: "skipped", constraint: "1.1.0", wantVersion: "1.1.0"},
+		{name: "strict newest", constraint: "1.1.0", strict: true, wantRotten: 1},
+		{name: "strict older", constraint: "1.0.0", strict: true, wantRotten: 2},
+		{name: "strict no match", constraint: ">= 2.0.0", strict: true, wantRotten: 2},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var events []Event
+
+			app := testApp(t, testRoot(t), Config{},
+				StrictFoods(tc.strict),
+				WithEventHandler(func(e Event) {
+					if e.Type == EventRottenFood {
+						events = append(events, e)
+					}
+				}),
+			)
+
+			version, err := app.resolve(rig, "foo", tc.constraint, nil)
+
+			if len(events) != 2 {
+				t.Errorf("want 2 RottenFood events, got %d", len(events))
+			}
+
+			if tc.wantRotten == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if version.food.Version != tc.wantVersion {
+					t.Errorf("want version %s, got %s", tc.wantVersion, version.food.Version)
+				}
+
+				return
+			}
+
+			var rotten ErrRottenFoods
+
+			if !errors.As(err, &rotten) {
+				t.Fatalf("want ErrRottenFoods, got %v", err)
+			}
+
+			if len(rotten) != tc.wantRotten {
+				t.Errorf("want %d rotten revisions, got %d: %v", tc.wantRotten, len(rotten), rotten)
+			}
+
+			var newest ErrRottenFood
+
+			if !errors.As(err, &newest) || newest.FoodCommitID != rotten[0].FoodCommitID {
+				t.Errorf("want the newest rotten revision, got %v", newest)
+			}
+		})
+	}
+}
+
+func TestResolveAllRottenFoods(t *testing.T) {
+	rig := testRig(t, `food = {`, `error("broken")`)
+	defer os.RemoveAll(rig)
+
+	_, err := testApp(t, testRoot(t), Config{}).resolve(rig, "foo", "", nil)
+
+	var rotten ErrRottenFoods
+
+	if !errors.As(err, &rotten) {
+		t.Fatalf("want ErrRottenFoods, got %v", err)
+	}
+
+	if len(rotten) != 2 {
+		t.Errorf("want 2 rotten revisions, got %v", rotten)
+	}
+}
+
+func TestSyncKeepGoing(t *testing.T) {
+	packages := newTestPackages(t)
+
+	rig1 := testRig(t, packages.food(t, "foo", "1.0.0", "linux/amd64"))
+	defer os.RemoveAll(rig1)
+
+	rig2 := testRig(t, fmt.Sprintf(`food = {
+  name = "foo",
+  version = "1.0.0",
+  packages = {
+    { os = "linux", arch = "amd64", url = %q, sha256 = "abc", resources = { { path = "foo", installpath = "bin/foo", executable = true } } },
+  },
+}`, packages.URL+"/missing.tar.gz"))
+	defer os.RemoveAll(rig2)
+
+	config := Config{Dependencies: []Dependency{
+		{Rig: rig1, Food: "foo", Version: "1.0.0"},
+		{Rig: rig1, Food: "bar", Version: "1.0.0"},
+		{Rig: rig2, Food: "foo", Version: "1.0.0"},
+	}}
+
+	for _, keepGoing := range []bool{false, true} {
+		t.Run(fmt.Sprintf("keep-going=%v", keepGoing), func(t *testing.T) {
+			root := testRoot(t)
+
+			err := testApp(t, root, config, Target("linux", "amd64"), KeepGoing(keepGoing)).Sync(config)
+
+			var (
+				deps        ErrDependencies
+				noMatching  ErrNoMatchingVersion
+				install     ErrInstall
+				wantInstall = keepGoing
+			)
+
+			if keepGoing != errors.As(err, &deps) {
+				t.Fatalf("want ErrDependencies: %v, got %v", keepGoing, err)
+			}
+
+			if keepGoing && (len(deps) != 2 || deps[0].Food != "bar" || deps[1].Rig != rig2) {
+				t.Errorf("want the 2 failed dependencies, got %v", deps)
+			}
+
+			if !errors.As(err, &noMatching) || noMatching.Food != "bar" {
+				t.Errorf("want ErrNoMatchingVersion for bar, got %v", err)
+			}
+
+			// Without keep-going, sync stops at bar
+			if errors.As(err, &install) != wantInstall {
+				t.Errorf("want ErrInstall: %v, got %v", wantInstall, err)
+			}
+
+			// Nothing is made current when any dependency failed
+			if _, err := os.Stat(filepath.Join(root, "bin", "foo")); !os.IsNotExist(err) {
+				t.Errorf("want foo not to be linked, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSyncCaveats(t *testing.T) {
+	packages := newTestPackages(t)
+
+	food := strings.Replace(packages.food(t, "foo", "1.0.0", "linux/amd64"), "food = {\n", "food = {\n  caveats = \"run foo init\",\n", 1)
+
+	rig := testRig(t, food)
+	defer os.RemoveAll(rig)
+
+	config := Config{Dependencies: []Dependency{{Rig: rig, Food: "foo", Version: "1.0.0"}}}
+
+	var installed []Event
+
+	handler := WithEventHandler(func(e Event) {
+		if e.Type == EventInstalled {
+			installed = append(installed, e)
+		}
+	})
+
+	if err := testApp(t, testRoot(t), config, Target("linux", "amd64"), handler).Sync(config); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(installed) != 1 || installed[0].Caveats != "run foo init" {
+		t.Errorf("want the caveats in the Installed event, got %+v", installed)
+	}
+}
+
+func TestSyncInstalled(t *testing.T) {
+	packages := newTestPackages(t)
+
+	rig := testRig(t, packages.food(t, "foo", "1.0.0", "linux/amd64"))
+	defer os.RemoveAll(rig)
+
+	root := testRoot(t)
+
+	config := Config{Dependencies: []Dependency{{Rig: rig, Food: "foo", Version: "1.0.0"}}}
+
+	// sync returns the type of the event emitted for installing or skipping foo
+	sync := func(opts ...Option) EventType {
+		t.Helper()
+
+		var got EventType
+
+		handler := WithEventHandler(func(e Event) {
+			if e.Type == EventInstalled || e.Type == EventSkipped {
+				got = e.Type
+			}
+		})
+
+		if err := testApp(t, root, config, append([]Option{Target("linux", "amd64"), handler}, opts...)...).Sync(config); err != nil {
+			t.Fatal(err)
+		}
+
+		return got
+	}
+
+	testcases := []struct {
+		name   string
+		before func()
+		opts   []Option
+		want   EventType
+	}{
+		{name: "new", want: EventInstalled},
+		{name: "installed", want: EventSkipped},
+		{
+			name: "missing link",
+			before: func() {
+				if err := os.Remove(filepath.Join(root, "bin", "foo")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: EventInstalled,
+		},
+		{name: "force", opts: []Option{Force(true)}, want: EventInstalled},
+	}
+
+	// The cases run in order, each syncing the root left by the previous one
+	for _, tc := range testcases {
+		if tc.before != nil {
+			tc.before()
+		}
+
+		if got := sync(tc.opts...); got != tc.want {
+			t.Errorf("%s: want %s, got %s", tc.name, tc.want, got)
+		}
+
+		if got, want := readLink(t, filepath.Join(root, "bin"), "foo"), packageContent("foo", "1.0.0", "linux/amd64"); got != want {
+			t.Errorf("%s: want foo 1.0.0 to be linked, got %q", tc.name, got)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package shoal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/fishworks/gofish"
+)
+
+const installedFoodsFilename = "installed.json"
+
+// InstalledFood records a food version installed by shoal.
+type InstalledFood struct {
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Rig          string `json:"rig"`
+	FoodCommitID string `json:"foodCommitID"`
+	SHA256       string `json:"sha256"`
+	// OS and Arch are the platform of the installed package.
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+	// InstallPaths are the paths to the links to the food's resources, like `.shoal/bin/helm`.
+	InstallPaths []string `json:"installPaths"`
+}
+
+// InstalledFoods returns the foods installed in the current generation, keyed by food name.
+// Unlike Sync, it doesn't move the bin dir created by an older version of shoal into a generation,
+// so that Plan and Info don't change anything.
+func (a *App) InstalledFoods() (map[string]InstalledFood, error) {
+	a.setEnv()
+
+	a.generationMutex.Lock()
+	defer a.generationMutex.Unlock()
+
+	gen, err := a.findCurrentGeneration()
+	if err != nil {
+		return nil, err
+	}
+
+	if gen == nil {
+		a.stateMutex.Lock()
+		defer a.stateMutex.Unlock()
+
+		return readInstalledFoods(filepath.Join(a.RootDir, installedFoodsFilename))
+	}
+
+	return a.installedFoods(gen)
+}
+
+func (a *App) installedFoods(gen *generation) (map[string]InstalledFood, error) {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	return readInstalledFoods(gen.installedFoodsPath())
+}
+
+func readInstalledFoods(path string) (map[string]InstalledFood, error) {
+	foods := map[string]InstalledFood{}
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return foods, nil
+		}
+		return nil, fmt.Errorf("reading installed foods: %w", err)
+	}
+
+	if err := json.Unmarshal(bs, &foods); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return foods, nil
+}
+
+func (a *App) recordInstalledFood(gen *generation, installed InstalledFood) error {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	path := gen.installedFoodsPath()
+
+	foods, err := readInstalledFoods(path)
+	if err != nil {
+		return err
+	}
+
+	foods[installed.Name] = installed
+
+	bs, err := json.MarshalIndent(foods, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, bs, 0644); err != nil {
+		return fmt.Errorf("writing installed foods: %w", err)
+	}
+
+	return nil
+}
+
+// newInstalledFood returns the record for the food installed from the package.
+func (a *App) newInstalledFood(rig string, v *versionedFood, pkg *gofish.Package) (InstalledFood, error) {
+	installed := InstalledFood{
+		Name:         v.food.Name,
+		Version:      v.food.Version,
+		Rig:          rig,
+		FoodCommitID: v.foodCommitID,
+		SHA256:       pkg.SHA256,
+		OS:           pkg.OS,
+		Arch:         pkg.Arch,
+	}
+
+	for _, r := range pkg.Resources {
+		p, err := linkPath(a.BinPath(), r)
+		if err != nil {
+			return installed, err
+		}
+
+		installed.InstallPaths = append(installed.InstallPaths, p)
+	}
+
+	return installed, nil
+}
+
+// upToDate returns true when the recorded food is the same version as the one from the package,
+// and all the links to its resources in binDir still point to existing files in the barrel.
+func (i InstalledFood) upToDate(f *gofish.Food, pkg *gofish.Package, binDir string) bool {
+	if i.Version != f.Version || i.SHA256 != pkg.SHA256 || len(i.InstallPaths) != len(pkg.Resources) {
+		return false
+	}
+
+	// Foods recorded by older versions of shoal have no platform
+	if i.OS != "" && (i.OS != pkg.OS || i.Arch != pkg.Arch) {
+		return false
+	}
+
+	for _, r := range pkg.Resources {
+		p, err := linkPath(binDir, r)
+		if err != nil {
+			return false
+		}
+
+		link, err := os.Readlink(p)
+		if err != nil || link != filepath.Join(barrelDir(f, pkg), r.Path) {
+			return false
+		}
+
+		if _, err := os.Stat(link); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,199 @@
+package shoal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// renderConfig expands the environment variables and the Go template expressions in the config file,
+// so that CI can override versions and rigs without editing the file.
+//
+// `${NAME}` is replaced with the value of the environment variable, and fails when it is not set.
+// `${NAME:-default}` falls back to the default, and `${NAME:?message}` fails with the message when the variable is empty or not set.
+// `$${` is replaced with a literal `${`.
+//
+// Template expressions like `{{ env "NAME" | default ">= 3.3.0" }}` can use the following functions:
+//
+//   - `env NAME` returns the value of the environment variable, or an empty string
+//   - `requiredEnv NAME` returns the value of the environment variable, and fails when it is empty or not set
+//   - `default DEFAULT VALUE` returns VALUE, or DEFAULT when VALUE is empty
+//   - `required MESSAGE VALUE` returns VALUE, and fails with MESSAGE when VALUE is empty
+//
+// The template expressions are evaluated before `${NAME}` is expanded, so that the values of the environment variables
+// are used as they are, even when they look like template expressions or references to other variables.
+//
+// Comment lines are left as they are, so that a commented-out line doesn't fail on a variable that is not set.
+func renderConfig(data []byte, lookupEnv func(string) (string, bool)) ([]byte, error) {
+	rendered, err := renderTemplate(data, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	return expandEnv(rendered, lookupEnv)
+}
+
+func renderTemplate(data []byte, lookupEnv func(string) (string, bool)) ([]byte, error) {
+	funcs := template.FuncMap{
+		"env": func(name string) string {
+			v, _ := lookupEnv(name)
+			return escapeEnvVarRefs(v)
+		},
+		"requiredEnv": func(name string) (string, error) {
+			if v, _ := lookupEnv(name); v != "" {
+				return escapeEnvVarRefs(v), nil
+			}
+
+			return "", fmt.Errorf("environment variable %s is required but not set", name)
+		},
+		"default": func(def string, v interface{}) interface{} {
+			if v == nil || v == "" {
+				return def
+			}
+
+			return v
+		},
+		"required": func(msg string, v interface{}) (interface{}, error) {
+			if v == nil || v == "" {
+				return nil, errors.New(msg)
+			}
+
+			return v, nil
+		},
+	}
+
+	// Print the actions in comment lines as they are
+	expanded := commentLine.ReplaceAllFunc(data, func(line []byte) []byte {
+		return bytes.ReplaceAll(line, []byte("{{"), []byte(`{{"{{"}}`))
+	})
+
+	// Allow the expressions in double-quoted YAML strings, like `"{{ env \"NAME\" }}"`
+	expanded = templateAction.ReplaceAllFunc(expanded, func(action []byte) []byte {
+		return bytes.ReplaceAll(action, []byte(`\"`), []byte(`"`))
+	})
+
+	tmpl, err := template.New("config").Funcs(funcs).Option("missingkey=error").Parse(string(expanded))
+	if err != nil {
+		return nil, templateError(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return nil, templateError(err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var templateAction = regexp.MustCompile(`\{\{.*?\}\}`)
+
+// envVarRef matches either an escaped `$${`, or a reference to an environment variable
+var envVarRef = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:-|:\?)([^}]*))?\}`)
+
+var commentLine = regexp.MustCompile(`(?m)^[ \t]*#.*$`)
+
+// escapeEnvVarRefs escapes `${` in the value of an environment variable, so that it isn't expanded by expandEnv.
+func escapeEnvVarRefs(v string) string {
+	return strings.ReplaceAll(v, "${", "$${")
+}
+
+func expandEnv(data []byte, lookupEnv func(string) (string, bool)) ([]byte, error) {
+	var (
+		buf  bytes.Buffer
+		errs []ConfigError
+		last int
+	)
+
+	comments := commentLine.FindAllIndex(data, -1)
+
+	inComment := func(i int) bool {
+		for _, c := range comments {
+			if c[0] <= i && i < c[1] {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, m := range envVarRef.FindAllSubmatchIndex(data, -1) {
+		if inComment(m[0]) {
+			continue
+		}
+
+		buf.Write(data[last:m[0]])
+		last = m[1]
+
+		// An escaped `$${`
+		if m[2] < 0 {
+			buf.WriteString("${")
+			continue
+		}
+
+		name := string(data[m[2]:m[3]])
+
+		var op, arg string
+
+		if m[4] >= 0 {
+			op, arg = string(data[m[4]:m[5]]), string(data[m[6]:m[7]])
+		}
+
+		v, ok := lookupEnv(name)
+
+		var msg string
+
+		switch {
+		case op == ":-" && v == "":
+			v = arg
+		case op == ":?" && v == "":
+			msg = arg
+			if msg == "" {
+				msg = fmt.Sprintf("environment variable %s is required but not set", name)
+			}
+		case op == "" && !ok:
+			msg = fmt.Sprintf("environment variable %s is not set", name)
+		}
+
+		if msg != "" {
+			errs = append(errs, ConfigError{Line: bytes.Count(data[:m[0]], []byte("\n")) + 1, Message: msg})
+		}
+
+		buf.WriteString(v)
+	}
+
+	if len(errs) > 0 {
+		return nil, ErrInvalidConfig{Errors: errs}
+	}
+
+	buf.Write(data[last:])
+
+	return buf.Bytes(), nil
+}
+
+var (
+	templateErrorLine = regexp.MustCompile(`^template: config:(\d+)(?::\d+)?: (.*)$`)
+	templateFuncError = regexp.MustCompile(`^executing "config" at <.*>: error calling \w+: (.*)$`)
+)
+
+// templateError converts the template error to an ErrInvalidConfig, so that it is reported like other errors in the config.
+func templateError(err error) error {
+	msg := err.Error()
+
+	var line int
+
+	if m := templateErrorLine.FindStringSubmatch(msg); m != nil {
+		line, _ = strconv.Atoi(m[1])
+		msg = m[2]
+	}
+
+	if m := templateFuncError.FindStringSubmatch(msg); m != nil {
+		msg = m[1]
+	}
+
+	return ErrInvalidConfig{Errors: []ConfigError{{Line: line, Message: msg}}}
+}
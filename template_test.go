@@ -0,0 +1,116 @@
+package shoal
+
+import (
+	"testing"
+)
+
+func TestRenderConfig(t *testing.T) {
+	env := map[string]string{
+		"HELM_VERSION": ">= 3.4.0",
+		"EMPTY":        "",
+		"TEMPLATE":     `{{ env "SECRET" }}`,
+		"BRACES":       "a{{b",
+		"REF":          "${SECRET}",
+		"SECRET":       "leaked",
+	}
+
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	testcases := []struct {
+		name string
+		yaml string
+		want string
+		err  string
+	}{
+		{
+			name: "env var",
+			yaml: "version: ${HELM_VERSION}",
+			want: "version: >= 3.4.0",
+		},
+		{
+			name: "env var default",
+			yaml: "version: ${KUBECTL_VERSION:-1.18}, ${EMPTY:-1.19}",
+			want: "version: 1.18, 1.19",
+		},
+		{
+			name: "unset env var",
+			yaml: "rig: r\nversion: ${KUBECTL_VERSION}",
+			err:  "line 2: environment variable KUBECTL_VERSION is not set",
+		},
+		{
+			name: "required env var",
+			yaml: "rig: ${RIG:?set RIG to the rig URL}",
+			err:  "line 1: set RIG to the rig URL",
+		},
+		{
+			name: "escaped env var",
+			yaml: "version: $${HELM_VERSION}, $${ not a variable",
+			want: "version: ${HELM_VERSION}, ${ not a variable",
+		},
+		{
+			name: "env var looking like a template",
+			yaml: "rig: ${TEMPLATE}\nversion: ${BRACES}",
+			want: "rig: {{ env \"SECRET\" }}\nversion: a{{b",
+		},
+		{
+			name: "template env looking like an env var",
+			yaml: `rig: {{ env "REF" }}, {{ requiredEnv "REF" }}`,
+			want: "rig: ${SECRET}, ${SECRET}",
+		},
+		{
+			name: "comment",
+			yaml: "rig: r\n# rig: ${INTERNAL_RIG_URL}\n  # version: {{ requiredEnv \"KUBECTL_VERSION\" }}, $${X}\nversion: ${HELM_VERSION}",
+			want: "rig: r\n# rig: ${INTERNAL_RIG_URL}\n  # version: {{ requiredEnv \"KUBECTL_VERSION\" }}, $${X}\nversion: >= 3.4.0",
+		},
+		{
+			name: "template",
+			yaml: `version: "{{ env "HELM_VERSION" | default ">= 3.3.0" }}", "{{ env "KUBECTL_VERSION" | default ">= 1.18" }}"`,
+			want: `version: ">= 3.4.0", ">= 1.18"`,
+		},
+		{
+			name: "template in double-quoted string",
+			yaml: `version: "{{ env \"HELM_VERSION\" | default \">= 3.3.0\" }}"`,
+			want: `version: ">= 3.4.0"`,
+		},
+		{
+			name: "template required env",
+			yaml: "rig: r\n\nversion: {{ requiredEnv \"KUBECTL_VERSION\" }}",
+			err:  "line 3: environment variable KUBECTL_VERSION is required but not set",
+		},
+		{
+			name: "template required",
+			yaml: `rig: {{ env "RIG" | required "RIG is required" }}`,
+			err:  "line 1: RIG is required",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := renderConfig([]byte(tc.yaml), lookupEnv)
+
+			if tc.err != "" {
+				invalid, ok := err.(ErrInvalidConfig)
+				if !ok || len(invalid.Errors) != 1 {
+					t.Fatalf("want ErrInvalidConfig with 1 error, got %v", err)
+				}
+
+				if e := invalid.Errors[0].Error(); e != tc.err {
+					t.Errorf("want %q, got %q", tc.err, e)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(got) != tc.want {
+				t.Errorf("want %q, got %q", tc.want, string(got))
+			}
+		})
+	}
+}
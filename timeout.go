@@ -0,0 +1,21 @@
+package shoal
+
+import (
+	"time"
+)
+
+// WithGitTimeout sets the maximum duration of each git operation. The git process is killed when it exceeds the timeout.
+// A non-zero duration takes precedence over the one in the config.
+func WithGitTimeout(d time.Duration) Option {
+	return func(app *App) {
+		app.overrides.gitTimeout = d
+	}
+}
+
+// WithDownloadTimeout sets the maximum duration of each package download, including reading the response body.
+// A non-zero duration takes precedence over the one in the config.
+func WithDownloadTimeout(d time.Duration) Option {
+	return func(app *App) {
+		app.overrides.downloadTimeout = d
+	}
+}
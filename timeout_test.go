@@ -0,0 +1,211 @@
+package shoal
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fishworks/gofish"
+)
+
+// stalledServer accepts requests but never responds until it is closed.
+func stalledServer() (*httptest.Server, func()) {
+	done := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+
+	return srv, func() {
+		close(done)
+		srv.Close()
+	}
+}
+
+func TestDownloadTimeout(t *testing.T) {
+	srv, stop := stalledServer()
+	defer stop()
+
+	dir, err := ioutil.TempDir("", "shoal-timeout-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	app, err := New(LogOutput(ioutil.Discard), WithDownloadTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := srv.URL + "/foo"
+
+	err = app.downloadFile(&gofish.Food{Name: "foo", Version: "1.0.0"}, url, filepath.Join(dir, "foo"))
+
+	var timeout ErrTimeout
+
+	if !errors.As(err, &timeout) {
+		t.Fatalf("want ErrTimeout, got %v", err)
+	}
+
+	if timeout.URL != url {
+		t.Errorf("want URL %q, got %q", url, timeout.URL)
+	}
+
+	if !isRetryableDownloadError(err) {
+		t.Errorf("want timeouts to be retryable")
+	}
+}
+
+func TestSyncDownloadError(t *testing.T) {
+	srv, stop := stalledServer()
+	defer stop()
+
+	packages := newTestPackages(t)
+
+	testcases := []struct {
+		name  string
+		url   string
+		check func(error) bool
+	}{
+		{name: "timeout", url: srv.URL + "/foo.tar.gz", check: func(err error) bool {
+			var timeout ErrTimeout
+			return errors.As(err, &timeout) && timeout.URL == srv.URL+"/foo.tar.gz"
+		}},
+		{name: "http status", url: packages.URL + "/missing.tar.gz", check: func(err error) bool {
+			var status ErrHTTPStatus
+			return errors.As(err, &status) && status.StatusCode == http.StatusNotFound
+		}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rig := testRig(t, fmt.Sprintf(`food = {
+  name = "foo",
+  version = "1.0.0",
+  packages = {
+    { os = "linux", arch = "amd64", url = %q, sha256 = "abc", resources = { { path = "foo", installpath = "bin/foo", executable = true } } },
+  },
+}`, tc.url))
+			defer os.RemoveAll(rig)
+
+			config := Config{
+				Dependencies: []Dependency{{Rig: rig, Food: "foo", Version: "1.0.0"}},
+				Download:     Download{Retry: RetryPolicy{Attempts: 1}, Timeout: 100 * time.Millisecond},
+			}
+
+			err := testApp(t, testRoot(t), config, Target("linux", "amd64")).Sync(config)
+
+			if !tc.check(err) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNativeGitTimeout(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	srv, stop := stalledServer()
+	defer stop()
+
+	dir, err := ioutil.TempDir("", "shoal-timeout-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := &NativeGit{Timeout: 500 * time.Millisecond}
+
+	rig := srv.URL + "/rig.git"
+
+	start := time.Now()
+
+	err = g.Clone(rig, filepath.Join(dir, "rig"))
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("want git clone to be killed on timeout, took %s", elapsed)
+	}
+
+	var timeout ErrTimeout
+
+	if !errors.As(err, &timeout) {
+		t.Fatalf("want ErrTimeout, got %v", err)
+	}
+
+	if timeout.Rig != rig {
+		t.Errorf("want rig %q, got %q", rig, timeout.Rig)
+	}
+}
+
+func TestTimeoutsFromConfig(t *testing.T) {
+	config := Config{Git: Git{Timeout: time.Minute}, Download: Download{Timeout: 2 * time.Minute}}
+
+	app := testApp(t, testRoot(t), config)
+
+	if g := app.git.(*retryingGit).GitClient.(*NativeGit); g.Timeout != time.Minute {
+		t.Errorf("want the git timeout in the config, got %s", g.Timeout)
+	}
+
+	if app.downloadTimeout != 2*time.Minute {
+		t.Errorf("want the download timeout in the config, got %s", app.downloadTimeout)
+	}
+
+	// The options take precedence over the config
+	app = testApp(t, testRoot(t), config, WithGitTimeout(time.Second), WithDownloadTimeout(2*time.Second))
+
+	if g := app.git.(*retryingGit).GitClient.(*NativeGit); g.Timeout != time.Second {
+		t.Errorf("want the git timeout in the option, got %s", g.Timeout)
+	}
+
+	if app.downloadTimeout != 2*time.Second {
+		t.Errorf("want the download timeout in the option, got %s", app.downloadTimeout)
+	}
+}
+
+// timingOutGit fails every fetch with a timeout that doesn't know the rig, like the GitClients run in a workspace do.
+type timingOutGit struct {
+	GitClient
+}
+
+func (g timingOutGit) Fetch(workspaceDir, ref string) error {
+	return ErrTimeout{Operation: "git fetch", Timeout: time.Second}
+}
+
+func TestWorkspaceTimeoutRig(t *testing.T) {
+	rig := testRig(t, fooRevision("1.0.0"))
+	defer os.RemoveAll(rig)
+
+	root := testRoot(t)
+
+	// Clone the rig, so that the next app fetches it
+	if _, err := testApp(t, root, Config{}).workspace(rig); err != nil {
+		t.Fatal(err)
+	}
+
+	app := testApp(t, root, Config{})
+	app.git = timingOutGit{&NativeGit{}}
+
+	_, err := app.workspace(rig)
+
+	var timeout ErrTimeout
+
+	if !errors.As(err, &timeout) {
+		t.Fatalf("want ErrTimeout, got %v", err)
+	}
+
+	if timeout.Rig != rig {
+		t.Errorf("want rig %q, got %q", rig, timeout.Rig)
+	}
+}
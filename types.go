@@ -1,39 +1,115 @@
 package shoal
 
+import "time"
+
 type Config struct {
-	Git Git `yaml:"git"`
+	Include []Include `yaml:"include,omitempty"`
+
+	Git Git `yaml:"git,omitempty"`
+
+	Rig string `yaml:"rig,omitempty"`
+
+	Mirrors  []Mirror `yaml:"mirrors,omitempty"`
+	HTTP     HTTP     `yaml:"http,omitempty"`
+	Download Download `yaml:"download,omitempty"`
+
+	Foods Foods `yaml:"foods,omitempty"`
+	Helm  Helm  `yaml:"helm,omitempty"`
+
+	Dependencies []Dependency `yaml:"dependencies,omitempty"`
+}
+
+// Include is another config file to be merged into the config.
+// It is either a local file, or a file in a git repository at a ref.
+// A plain string is a shorthand for a local file.
+type Include struct {
+	// Path is the path to the config file. For a local file, it is relative to the including config file.
+	// For a file in a git repository, it is relative to the root of the repository.
+	Path string `yaml:"path"`
+	// Git is the URL of the git repository to read the file from.
+	Git string `yaml:"git,omitempty"`
+	// Ref is the branch, tag, or commit to read the file at. Defaults to the default branch of the repository.
+	Ref string `yaml:"ref,omitempty"`
+}
 
-	Rig string `yaml:"rig"`
+// Mirror rewrites package URLs and rig git URLs starting with Prefix, so that they are downloaded or cloned from the mirror.
+// The sha256 of packages are still verified against the food definitions read from the original rigs.
+type Mirror struct {
+	// Prefix is the prefix of the original URLs, like `https://github.com/`.
+	Prefix string `yaml:"prefix"`
+	// URL replaces the prefix, like `https://artifactory.example.com/github/`.
+	URL string `yaml:"url"`
+}
 
-	Foods Foods `yaml:"foods"`
-	Helm  Helm  `yaml:"helm"`
+// HTTP configures the HTTP client used for downloading packages and for cloning rigs with the go-git provider.
+// The native provider uses the git config, like `http.proxy` and `http.sslCAInfo`, instead.
+type HTTP struct {
+	// Proxy is the URL of the HTTP proxy.
+	// Defaults to the one in the HTTPS_PROXY or HTTP_PROXY envvar, excluding the hosts in the NO_PROXY envvar.
+	Proxy string `yaml:"proxy,omitempty"`
+	// CABundle is the path to a PEM file containing CA certificates to trust in addition to the system ones.
+	// Defaults to the SHOAL_CA_BUNDLE envvar.
+	CABundle string `yaml:"caBundle,omitempty"`
+	// InsecureSkipVerify disables the verification of TLS certificates. Use it only for local test servers.
+	// It is a pointer so that a config can set it back to false over the included one.
+	InsecureSkipVerify *bool `yaml:"insecureSkipVerify,omitempty"`
+}
 
-	Dependencies []Dependency `yaml:"dependencies"`
+func (h HTTP) insecureSkipVerify() bool {
+	return h.InsecureSkipVerify != nil && *h.InsecureSkipVerify
 }
 
 type Git struct {
-	Provider string `yaml:"provider"`
+	Provider string `yaml:"provider,omitempty"`
+
+	Retry RetryPolicy `yaml:"retry,omitempty"`
+	// Timeout is the maximum duration of each git operation, like `5m`. Each retry gets its own timeout.
+	// Zero means no timeout.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+type Download struct {
+	Retry RetryPolicy `yaml:"retry,omitempty"`
+	// Timeout is the maximum duration of each package download, like `10m`. Each retry gets its own timeout.
+	// Zero means no timeout.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
 }
 
 type Dependency struct {
 	Rig     string `yaml:"rig"`
 	Food    string `yaml:"food"`
-	Version string `yaml:"version"`
+	Version string `yaml:"version,omitempty"`
+
+	// OS and Arch limit the dependency to the operating systems and the architectures, like `linux` and `arm64`.
+	// The dependency is installed on any platform when they are empty.
+	OS   Selector `yaml:"os,omitempty"`
+	Arch Selector `yaml:"arch,omitempty"`
+
+	// Versions overrides Version on the platforms, keyed by an OS like `darwin` or a pair of OS and arch like `linux/arm64`.
+	// The OS/arch key takes precedence over the OS key.
+	Versions map[string]string `yaml:"versions,omitempty"`
+
+	// Vars are exposed to the food definition as `shoal.vars`, so that a food can compute the URLs of custom variants,
+	// like FIPS builds.
+	Vars map[string]string `yaml:"vars,omitempty"`
 }
 
+// Selector is a list of values to match against. A plain string is a shorthand for a single value.
+type Selector []string
+
 type Foods struct {
-	Helmfile string `yaml:"helmfile"`
-	Helm     string `yaml:"helm"`
-	Kubectl  string `yaml:"kubectl"`
-	Eksctl   string `yaml:"eksctl"`
+	Helmfile string `yaml:"helmfile,omitempty"`
+	Helm     string `yaml:"helm,omitempty"`
+	Kubectl  string `yaml:"kubectl,omitempty"`
+	Eksctl   string `yaml:"eksctl,omitempty"`
 
 	Others map[string]string `yaml:",inline"`
 }
 
 type Helm struct {
-	Plugins HelmPlugins `yaml:"plugins"`
+	Plugins HelmPlugins `yaml:"plugins,omitempty"`
 }
 
 type HelmPlugins struct {
-	Diff string `yaml:"diff"`
+	Diff string `yaml:"diff,omitempty"`
 }
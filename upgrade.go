@@ -0,0 +1,157 @@
+package shoal
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/Masterminds/semver"
+)
+
+// UpgradeLimit limits how far Upgrade bumps the version constraints.
+type UpgradeLimit int
+
+const (
+	// UpgradeMajor allows upgrading to any newer version.
+	UpgradeMajor UpgradeLimit = iota
+	// UpgradeMinor allows upgrading to newer minor and patch versions of the same major version.
+	UpgradeMinor
+	// UpgradePatch allows upgrading to newer patch versions of the same minor version.
+	UpgradePatch
+)
+
+// Upgrade is the result of upgrading the version constraint of a dependency.
+type Upgrade struct {
+	Rig  string `json:"rig"`
+	Food string `json:"food"`
+	// Line is the line of the constraint in the config file.
+	Line int    `json:"line"`
+	From string `json:"from"`
+	// To is the new constraint. It equals From when the dependency is up to date or skipped.
+	To string `json:"to"`
+	// Skipped explains why the constraint was left as it is, like when it is a range of versions.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Upgraded returns true when the constraint was changed.
+func (u Upgrade) Upgraded() bool {
+	return u.To != u.From
+}
+
+var upgradableConstraint = regexp.MustCompile(`^(=|>=|~|\^)?(\s*)(\S+)$`)
+
+// Upgrade bumps the version constraints of the dependencies declared in the config file, in either `dependencies` or `foods`,
+// to the newest versions found in the history of their rigs within the limit. Prereleases are never selected.
+//
+// Exact versions are replaced with the newest version, and constraints like `>= 0.125.0`, `~1.2.3` and `^1.2.3`
+// keep their operators, like `>= 0.130.1`. Other constraints, and the ones using environment variables or templates, are skipped.
+// The overrides in `versions` are left as they are.
+//
+// Only the named foods are upgraded, or all of them when none is given.
+// The file is edited but not saved, so that the caller can review the changes.
+func (a *App) Upgrade(file *ConfigFile, limit UpgradeLimit, foods ...string) ([]Upgrade, error) {
+	a.setEnv()
+
+	deps := file.declaredDependencies()
+
+	declared := map[string]bool{}
+
+	for _, d := range deps {
+		declared[d.food] = true
+	}
+
+	selected := map[string]bool{}
+
+	for _, food := range foods {
+		if !declared[food] {
+			return nil, fmt.Errorf("food %q is not declared with a version in %s", food, file.path)
+		}
+
+		selected[food] = true
+	}
+
+	var upgrades []Upgrade
+
+	for _, d := range deps {
+		if len(selected) > 0 && !selected[d.food] {
+			continue
+		}
+
+		u := Upgrade{Rig: d.rig, Food: d.food, Line: d.node.Line, From: d.version, To: d.version}
+
+		m := upgradableConstraint.FindStringSubmatch(d.version)
+
+		var current *semver.Version
+
+		if m != nil {
+			current, _ = semver.NewVersion(m[3])
+		}
+
+		switch {
+		case templateAction.MatchString(d.version) || envVarRef.MatchString(d.version):
+			u.Skipped = "the constraint uses environment variables or templates"
+		case current == nil:
+			u.Skipped = "only exact versions and constraints like >= 1.2.3, ~1.2.3 and ^1.2.3 can be upgraded"
+		}
+
+		if u.Skipped != "" {
+			upgrades = append(upgrades, u)
+			continue
+		}
+
+		rig, err := renderConfig([]byte(d.rig), os.LookupEnv)
+		if err != nil {
+			return nil, inFile(err, file.path)
+		}
+
+		u.Rig = string(rig)
+
+		versions, rotten, err := a.listVersions(u.Rig, d.food, nil)
+		if err != nil {
+			return nil, ErrDependency{Rig: u.Rig, Food: d.food, Constraint: d.version, Err: err}
+		}
+
+		a.reportRottenFoods(d.food, rotten)
+
+		if newest, ok := newestWithin(versions, current, limit); ok {
+			u.To = m[1] + m[2] + newest
+
+			if err := file.setConstraint(u.Line, u.To); err != nil {
+				return nil, err
+			}
+		}
+
+		upgrades = append(upgrades, u)
+	}
+
+	return upgrades, nil
+}
+
+// newestWithin returns the newest version greater than the current one within the limit.
+func newestWithin(versions []versionedFood, current *semver.Version, limit UpgradeLimit) (string, bool) {
+	var (
+		newest  string
+		highest *semver.Version
+	)
+
+	for _, v := range versions {
+		sv, err := semver.NewVersion(v.food.Version)
+		if err != nil || sv.Prerelease() != "" || !sv.GreaterThan(current) {
+			continue
+		}
+
+		if limit >= UpgradeMinor && sv.Major() != current.Major() {
+			continue
+		}
+
+		if limit >= UpgradePatch && sv.Minor() != current.Minor() {
+			continue
+		}
+
+		if highest == nil || sv.GreaterThan(highest) {
+			newest, highest = v.food.Version, sv
+		}
+	}
+
+	return newest, highest != nil
+}
@@ -0,0 +1,99 @@
+package shoal
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestUpgrade(t *testing.T) {
+	rig := testRig(t,
+		fooRevision("1.2.0"),
+		fooRevision("1.2.3"),
+		fooRevision("1.3.1"),
+		fooRevision("2.0.0"),
+		fooRevision("2.1.0-rc.1"),
+	)
+	defer os.RemoveAll(rig)
+
+	app := testApp(t, testRoot(t), Config{})
+
+	config := fmt.Sprintf(`rig: &rig %s
+
+foods:
+  foo: 1.2.0 # pinned
+
+dependencies:
+- rig: *rig
+  food: foo
+  version: ">= 1.2.0"
+- rig: *rig
+  food: foo
+  version: "~1.2.0"
+- rig: *rig
+  food: foo
+  version: ">= 1.0, < 2"
+`, rig)
+
+	testcases := []struct {
+		name  string
+		limit UpgradeLimit
+		want  []string
+	}{
+		{name: "major", limit: UpgradeMajor, want: []string{"2.0.0", ">= 2.0.0", "~2.0.0", ">= 1.0, < 2"}},
+		{name: "minor", limit: UpgradeMinor, want: []string{"1.3.1", ">= 1.3.1", "~1.3.1", ">= 1.0, < 2"}},
+		{name: "patch", limit: UpgradePatch, want: []string{"1.2.3", ">= 1.2.3", "~1.2.3", ">= 1.0, < 2"}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := openTestConfigFile(t, config)
+
+			upgrades, err := app.Upgrade(f, tc.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+
+			for _, u := range upgrades {
+				got = append(got, u.To)
+			}
+
+			if !equalStrings(got, tc.want) {
+				t.Fatalf("want constraints %v, got %v", tc.want, got)
+			}
+
+			if u := upgrades[3]; u.Upgraded() || u.Skipped == "" {
+				t.Errorf("want the range skipped, got %+v", u)
+			}
+
+			want := fmt.Sprintf(`rig: &rig %s
+
+foods:
+  foo: %s # pinned
+
+dependencies:
+- rig: *rig
+  food: foo
+  version: "%s"
+- rig: *rig
+  food: foo
+  version: "%s"
+- rig: *rig
+  food: foo
+  version: ">= 1.0, < 2"
+`, rig, tc.want[0], tc.want[1], tc.want[2])
+
+			if got := string(f.Bytes()); got != want {
+				t.Errorf("want:\n%s\ngot:\n%s", want, got)
+			}
+		})
+	}
+
+	f := openTestConfigFile(t, config)
+
+	if _, err := app.Upgrade(f, UpgradeMajor, "bar"); err == nil {
+		t.Error("want error for the undeclared food, got none")
+	}
+}
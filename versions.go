@@ -0,0 +1,126 @@
+package shoal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/semver"
+)
+
+// FoodVersion is a revision of the food definition found in the history of a rig.
+type FoodVersion struct {
+	Version      string `json:"version"`
+	FoodCommitID string `json:"foodCommitID"`
+	// Description is the subject of the commit that changed the food definition.
+	Description string `json:"description"`
+	// Date is the time the commit was made. It is zero when the GitClient doesn't implement GitDatedLogger.
+	Date time.Time `json:"date"`
+	// Packages are the platforms the food has packages for.
+	Packages []FoodPlatform `json:"packages"`
+}
+
+// FoodPlatform is an OS and arch pair, like linux/amd64.
+type FoodPlatform struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+func (p FoodPlatform) String() string {
+	return p.OS + "/" + p.Arch
+}
+
+// ListVersions returns every version of the food found in the history of the rig, newest first.
+// The rig is cloned or fetched as it is for Ensure. Rotten revisions are skipped and reported as RottenFood events.
+func (a *App) ListVersions(rig, food string) ([]FoodVersion, error) {
+	a.setEnv()
+
+	versions, rotten, err := a.listVersions(rig, food, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	a.reportRottenFoods(food, rotten)
+
+	var list []FoodVersion
+
+	for _, v := range versions {
+		list = append(list, v.foodVersion())
+	}
+
+	return list, nil
+}
+
+// Resolve selects the version of the food satisfying the constraint as Ensure does, without installing it.
+// It is useful for validating a dependency before adding it to the config.
+func (a *App) Resolve(rig, food, constraint string) (*FoodVersion, error) {
+	a.setEnv()
+
+	v, err := a.resolve(rig, food, constraint, nil)
+	if err != nil {
+		return nil, ErrDependency{Rig: rig, Food: food, Constraint: constraint, Err: err}
+	}
+
+	fv := v.foodVersion()
+
+	return &fv, nil
+}
+
+func (v versionedFood) foodVersion() FoodVersion {
+	fv := FoodVersion{
+		Version:      v.food.Version,
+		FoodCommitID: v.foodCommitID,
+		Description:  v.description,
+		Date:         v.date,
+	}
+
+	for _, pkg := range v.food.Packages {
+		fv.Packages = append(fv.Packages, FoodPlatform{OS: pkg.OS, Arch: pkg.Arch})
+	}
+
+	return fv
+}
+
+// MatchingVersions returns the versions that satisfy the semver constraint, in the same order.
+// Versions that aren't valid semver never match.
+func MatchingVersions(versions []FoodVersion, constraint string) ([]FoodVersion, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing semver constraint from %q: %w", constraint, err)
+	}
+
+	var matching []FoodVersion
+
+	for _, v := range versions {
+		sv, err := semver.NewVersion(v.Version)
+		if err != nil {
+			continue
+		}
+
+		if c.Check(sv) {
+			matching = append(matching, v)
+		}
+	}
+
+	return matching, nil
+}
+
+// NewestVersion returns the version with the highest semver, or false when none of the versions is valid semver.
+func NewestVersion(versions []FoodVersion) (FoodVersion, bool) {
+	var (
+		newest  FoodVersion
+		highest *semver.Version
+	)
+
+	for _, v := range versions {
+		sv, err := semver.NewVersion(v.Version)
+		if err != nil {
+			continue
+		}
+
+		if highest == nil || sv.GreaterThan(highest) {
+			newest, highest = v, sv
+		}
+	}
+
+	return newest, highest != nil
+}
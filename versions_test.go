@@ -0,0 +1,72 @@
+package shoal
+
+import (
+	"os"
+	"testing"
+)
+
+func TestListVersions(t *testing.T) {
+	rig := testRig(t,
+		fooRevision("1.0.0"),
+		`food = { name = "foo", version = "1.1.0", packages = { { os = "linux", arch = "amd64" }, { os = "darwin", arch = "amd64" } } }`,
+		`food = {`,
+		fooRevision("2.0.0"),
+	)
+	defer os.RemoveAll(rig)
+
+	for _, provider := range []string{"native", "go-git"} {
+		t.Run(provider, func(t *testing.T) {
+			testListVersions(t, rig, provider)
+		})
+	}
+}
+
+func testListVersions(t *testing.T, rig, provider string) {
+	app := testApp(t, testRoot(t), Config{Git: Git{Provider: provider}})
+
+	versions, err := app.ListVersions(rig, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+
+	for _, v := range versions {
+		got = append(got, v.Version)
+
+		if v.FoodCommitID == "" || v.Date.IsZero() || v.Description == "" {
+			t.Errorf("want commit ID, date and description, got %+v", v)
+		}
+	}
+
+	if want := []string{"2.0.0", "1.1.0", "1.0.0"}; !equalStrings(got, want) {
+		t.Fatalf("want versions %v, got %v", want, got)
+	}
+
+	if p := versions[1].Packages; len(p) != 2 || p[0].String() != "linux/amd64" || p[1].String() != "darwin/amd64" {
+		t.Errorf("unexpected packages: %v", p)
+	}
+
+	matching, err := MatchingVersions(versions, "~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matching) != 2 || matching[0].Version != "1.1.0" || matching[1].Version != "1.0.0" {
+		t.Errorf("unexpected matching versions: %+v", matching)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}